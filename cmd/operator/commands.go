@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [command]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Runs the Operator service if no command is provided.")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	fmt.Fprintln(os.Stderr, "  status         list open pull requests and their validation results")
+	fmt.Fprintln(os.Stderr, "  stats [days]   show review queue statistics for the past week, or number of days")
+}
+
+func runCommand(pool *pgx.ConnPool, command string, args []string) {
+	var err error
+	switch command {
+	case "status":
+		err = runStatus(pool, args)
+	case "stats":
+		err = runStats(pool, args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		os.Exit(1)
+	}
+}
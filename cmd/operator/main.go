@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,8 +11,12 @@ import (
 	"github.com/jackc/pgx"
 	"github.com/karashiiro/operator/pkg/db"
 	"github.com/karashiiro/operator/pkg/inbox"
+	"github.com/karashiiro/operator/pkg/publish"
+	"github.com/karashiiro/operator/pkg/pullrequests"
 	"github.com/karashiiro/operator/pkg/reports"
 	"github.com/karashiiro/operator/pkg/sql"
+	"github.com/karashiiro/operator/pkg/stats"
+	"github.com/karashiiro/operator/pkg/webhook"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/reugn/go-quartz/quartz"
 )
@@ -31,22 +36,9 @@ func applyMigrations(pool *pgx.ConnPool) {
 	}
 }
 
-func main() {
-	// Create the database connection pool
-	config := &pgx.ConnConfig{
-		User:     "operator",
-		Password: "operator",
-		Database: "operator",
-	}
-
-	postgresHost := os.Getenv("OPERATOR_POSTGRES")
-	if postgresHost != "" {
-		log.Printf("Using PostgreSQL host %s\n", postgresHost)
-		config.Host = postgresHost
-	}
-
+func connect() *pgx.ConnPool {
 	pool, err := pgx.NewConnPool(pgx.ConnPoolConfig{
-		ConnConfig:     *config,
+		ConnConfig:     db.Config(),
 		MaxConnections: 4,
 		AfterConnect: func(c *pgx.Conn) error {
 			log.Println("Database connection opened")
@@ -57,20 +49,66 @@ func main() {
 		log.Printf("Unable to create database connection pool: %v\n", err)
 		os.Exit(1)
 	}
+
+	return pool
+}
+
+func main() {
+	// Create the database connection pool
+	pool := connect()
 	defer pool.Close()
 
 	// Apply the database migrations
 	applyMigrations(pool)
 
+	// Run a one-off command instead of the service, if one was provided
+	if len(os.Args) > 1 {
+		runCommand(pool, os.Args[1], os.Args[2:])
+		return
+	}
+
 	// Start the job scheduler
 	sched := quartz.NewStdScheduler()
 	sched.Start()
 
+	// Pull request updates are received through webhooks if a webhook
+	// secret is configured, which requires the HTTP server
+	httpAddr := os.Getenv("OPERATOR_HTTP_ADDR")
+	webhookSecret := os.Getenv("OPERATOR_WEBHOOK_SECRET")
+	webhooks := httpAddr != "" && webhookSecret != ""
+
+	// Schedule the pull request sync job. When webhooks are enabled, it only
+	// needs to catch anything they missed.
+	syncInterval := 2 * time.Minute
+	if webhooks {
+		syncInterval = 30 * time.Minute
+	}
+
+	syncTrigger := quartz.NewSimpleTrigger(syncInterval)
+	syncJob := pullrequests.SyncJob{Pool: pool}
+	sched.ScheduleJob(&syncJob, syncTrigger)
+
+	// Catch up on anything that changed while the Operator was down, instead
+	// of waiting for the first scheduled sync
+	go syncJob.Execute()
+
 	// Schedule the report job
 	reportTrigger := quartz.NewSimpleTrigger(2 * time.Minute)
 	reportJob := reports.ReportJob{Pool: pool}
 	sched.ScheduleJob(&reportJob, reportTrigger)
 
+	// Schedule the weekly statistics job. It runs hourly, and only emails
+	// readers whose last statistics are at least a week old.
+	statsTrigger := quartz.NewSimpleTrigger(time.Hour)
+	statsJob := stats.StatsJob{Pool: pool}
+	sched.ScheduleJob(&statsJob, statsTrigger)
+
+	// Schedule the stale queue job. It runs hourly, and only emails readers
+	// who are due to receive the digest.
+	staleTrigger := quartz.NewSimpleTrigger(time.Hour)
+	staleJob := reports.StaleJob{Pool: pool}
+	sched.ScheduleJob(&staleJob, staleTrigger)
+
 	// Schedule the email-checking job
 	receiveTrigger := quartz.NewSimpleTrigger(5 * time.Second)
 	receiveJob := inbox.ReceiveEmailsJob{
@@ -79,6 +117,58 @@ func main() {
 	}
 	sched.ScheduleJob(&receiveJob, receiveTrigger)
 
+	// Schedule the publish job, if enabled
+	githubComments := os.Getenv("OPERATOR_GITHUB_COMMENTS") == "true"
+	githubChecks, err := publish.ChecksFromEnv()
+	if err != nil {
+		log.Printf("Invalid OPERATOR_GITHUB_CHECKS: %v\n", err)
+		os.Exit(1)
+	}
+
+	githubLabels := os.Getenv("OPERATOR_GITHUB_LABELS") == "true"
+	if githubComments || githubChecks != publish.ChecksNone || githubLabels {
+		githubClient := publish.NewClient()
+		dryRun := publish.DryRun()
+		if githubClient == nil && !dryRun {
+			log.Println("OPERATOR_GITHUB_TOKEN is not set, not publishing validation results")
+		} else {
+			publishTrigger := quartz.NewSimpleTrigger(5 * time.Minute)
+			publishJob := publish.PublishJob{
+				Pool:     pool,
+				Client:   githubClient,
+				Comments: githubComments,
+				Checks:   githubChecks,
+				DryRun:   dryRun,
+			}
+
+			if githubLabels {
+				publishJob.Labels = publish.LabelsFromEnv()
+			}
+
+			sched.ScheduleJob(&publishJob, publishTrigger)
+		}
+	}
+
+	// Start the HTTP server, if enabled
+	if httpAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/unsubscribe", &inbox.UnsubscribeHandler{Pool: pool})
+		if webhooks {
+			mux.Handle("/webhook", &webhook.Handler{
+				Pool:   pool,
+				Secret: []byte(webhookSecret),
+			})
+		}
+
+		go func() {
+			log.Printf("Listening for HTTP requests on %s\n", httpAddr)
+			err := http.ListenAndServe(httpAddr, mux)
+			if err != nil {
+				log.Printf("HTTP server stopped: %v\n", err)
+			}
+		}()
+	}
+
 	// Block until SIGINT or SIGTERM is received
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/stats"
+)
+
+// runStats prints the review queue statistics for the past week, or for the
+// number of days provided.
+func runStats(pool *pgx.ConnPool, args []string) error {
+	period := stats.Period
+	if len(args) > 0 {
+		days, err := strconv.Atoi(args[0])
+		if err != nil || days <= 0 {
+			return fmt.Errorf("invalid number of days: %s", args[0])
+		}
+
+		period = time.Duration(days) * 24 * time.Hour
+	}
+
+	conn, err := pool.Acquire()
+	if err != nil {
+		return err
+	}
+	defer pool.Release(conn)
+
+	until := time.Now()
+	s, err := stats.Compute(conn, until.Add(-period), until)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "From %s to %s\n\n", s.Since.Format(time.RFC822), s.Until.Format(time.RFC822))
+	fmt.Fprintf(w, "Opened\t%d\n", s.Opened)
+	fmt.Fprintf(w, "Merged\t%d\n", s.Merged)
+	fmt.Fprintf(w, "Closed without merging\t%d\n", s.Closed)
+	fmt.Fprintf(w, "Open\t%d\n", s.Open)
+
+	fmt.Fprintln(w, "\nMETRIC\tPRS\tMEDIAN\tMEAN")
+	fmt.Fprintf(w, "Time to first review\t%d\t%s\n", s.FirstReview.Count, formatTurnaround(s.FirstReview))
+	fmt.Fprintf(w, "Time to merge\t%d\t%s\n", s.Merge.Count, formatTurnaround(s.Merge))
+	iterations := "-\t-"
+	if s.Iterations.Count > 0 {
+		iterations = fmt.Sprintf("%.1f\t%.1f", s.Iterations.Median, s.Iterations.Mean)
+	}
+	fmt.Fprintf(w, "Validation iterations before passing\t%d\t%s\n", s.Iterations.Count, iterations)
+
+	fmt.Fprintln(w, "\nDAY\tOPEN")
+	for _, q := range s.Queue {
+		fmt.Fprintf(w, "%s\t%d\n", q.Time.Format("Mon Jan 2"), q.Open)
+	}
+
+	if len(s.TopFindings) > 0 {
+		fmt.Fprintln(w, "\nFINDING\tPRS")
+		for _, f := range s.TopFindings {
+			fmt.Fprintf(w, "%s\t%d\n", f.Rule, f.PullRequests)
+		}
+	}
+
+	return w.Flush()
+}
+
+func formatTurnaround(t stats.Turnaround) string {
+	if t.Count == 0 {
+		return "-\t-"
+	}
+
+	return html.FormatDuration(t.Median) + "\t" + html.FormatDuration(t.Mean)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/pullrequests"
+)
+
+// runStatus prints the stored open pull requests and their most recent
+// validation results.
+func runStatus(pool *pgx.ConnPool, args []string) error {
+	conn, err := pool.Acquire()
+	if err != nil {
+		return err
+	}
+	defer pool.Release(conn)
+
+	prs, err := pullrequests.ListOpen(conn)
+	if err != nil {
+		return err
+	}
+
+	validations, err := pullrequests.GetOpenValidations(conn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PR\tTITLE\tSUBMITTER\tOPEN FOR\tLABELS\tREVIEW\tVALIDATION")
+	for _, pr := range prs {
+		labels := make([]string, len(pr.Labels))
+		for i, label := range pr.Labels {
+			labels[i] = label.GetName()
+		}
+
+		validation := "Not validated yet"
+		if v, ok := validations[pr.GetNumber()]; ok {
+			if v.Err != nil {
+				validation = fmt.Sprintf("error: %v", v.Err)
+			} else {
+				validation = v.Result.Summary()
+			}
+
+			if v.HeadSHA != pr.GetHead().GetSHA() {
+				validation += " (outdated)"
+			}
+		}
+
+		review := pr.Plogon().ReviewState.String()
+		if pr.GetDraft() {
+			review += " (draft)"
+		}
+
+		fmt.Fprintf(w, "#%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			pr.GetNumber(),
+			truncate(pr.GetTitle(), 50),
+			pr.GetUser().GetLogin(),
+			html.FormatDuration(time.Since(pr.GetCreatedAt())),
+			strings.Join(labels, ", "),
+			review,
+			validation)
+	}
+
+	return w.Flush()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n-1]) + "…"
+}
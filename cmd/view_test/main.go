@@ -4,56 +4,82 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"text/template"
 	"time"
 
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/db"
 	"github.com/karashiiro/operator/pkg/html"
 	"github.com/karashiiro/operator/pkg/reports"
 )
 
+func writeError(w http.ResponseWriter, err error) {
+	_, err = w.Write([]byte(fmt.Sprintf("%v\n", err)))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+var pool *pgx.ConnPool
+
+func renderReport() (*html.Body, error) {
+	conn, err := pool.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Release(conn)
+
+	reportTemplates, err := reports.GetReportTemplates(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Preview the report a new reader would receive
+	return reports.BuildTemplate(reports.BuildDigest(nil, reportTemplates, nil, time.Time{}))
+}
+
 func reportHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "text/html")
 
-	t, err := template.New("report.gohtml").Funcs(template.FuncMap{
-		"formatTime": func(t time.Time) string {
-			return t.Format(time.RFC822)
-		},
-	}).ParseFS(html.Files, "report.gohtml", "report-problems.gohtml")
+	body, err := renderReport()
 	if err != nil {
-		_, err := w.Write([]byte(fmt.Sprintf("%v\n", err)))
-		if err != nil {
-			log.Println(err)
-		}
-
+		writeError(w, err)
 		return
 	}
 
-	reportTemplates, err := reports.GetPlogonReportTemplates()
+	_, err = w.Write([]byte(body.HTML))
 	if err != nil {
-		_, err := w.Write([]byte(fmt.Sprintf("%v\n", err)))
-		if err != nil {
-			log.Println(err)
-		}
+		log.Println(err)
+	}
+}
 
+func reportTextHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+
+	body, err := renderReport()
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	err = t.Execute(w, struct {
-		PlogonStates []*reports.ReportTemplate
-	}{
-		PlogonStates: reportTemplates,
-	})
+	_, err = w.Write([]byte(body.Text))
 	if err != nil {
-		_, err := w.Write([]byte(fmt.Sprintf("%v\n", err)))
-		if err != nil {
-			log.Println(err)
-		}
-
-		return
+		log.Println(err)
 	}
 }
 
 func main() {
+	// Reports are built from the pull requests stored by the Operator
+	var err error
+	pool, err = pgx.NewConnPool(pgx.ConnPoolConfig{
+		ConnConfig:     db.Config(),
+		MaxConnections: 1,
+	})
+	if err != nil {
+		log.Fatalf("Unable to create database connection pool: %v\n", err)
+	}
+	defer pool.Close()
+
 	http.HandleFunc("/report", reportHandler)
+	http.HandleFunc("/report.txt", reportTextHandler)
 	http.ListenAndServe(":9000", nil)
 }
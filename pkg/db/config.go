@@ -0,0 +1,26 @@
+package db
+
+import (
+	"log"
+	"os"
+
+	"github.com/jackc/pgx"
+)
+
+// Config returns the database connection configuration. The host can be
+// overridden with OPERATOR_POSTGRES.
+func Config() pgx.ConnConfig {
+	config := pgx.ConnConfig{
+		User:     "operator",
+		Password: "operator",
+		Database: "operator",
+	}
+
+	postgresHost := os.Getenv("OPERATOR_POSTGRES")
+	if postgresHost != "" {
+		log.Printf("Using PostgreSQL host %s\n", postgresHost)
+		config.Host = postgresHost
+	}
+
+	return config
+}
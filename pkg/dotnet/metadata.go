@@ -0,0 +1,591 @@
+package dotnet
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+)
+
+// Version is a .NET assembly version.
+type Version struct {
+	Major    uint16
+	Minor    uint16
+	Build    uint16
+	Revision uint16
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Build, v.Revision)
+}
+
+// AssemblyRef is a reference from an assembly to another assembly.
+type AssemblyRef struct {
+	Name    string
+	Version Version
+}
+
+// Assembly is the identity of a .NET assembly, along with the assemblies it
+// references.
+type Assembly struct {
+	Name       string
+	Version    Version
+	References []*AssemblyRef
+}
+
+// Reference returns the referenced assembly with the provided name, or nil
+// if the assembly doesn't reference it.
+func (a *Assembly) Reference(name string) *AssemblyRef {
+	for _, ref := range a.References {
+		if ref.Name == name {
+			return ref
+		}
+	}
+
+	return nil
+}
+
+// Metadata table numbers, from ECMA-335 II.22
+const (
+	tableModule                 = 0x00
+	tableTypeRef                = 0x01
+	tableTypeDef                = 0x02
+	tableFieldPtr               = 0x03
+	tableField                  = 0x04
+	tableMethodPtr              = 0x05
+	tableMethodDef              = 0x06
+	tableParamPtr               = 0x07
+	tableParam                  = 0x08
+	tableInterfaceImpl          = 0x09
+	tableMemberRef              = 0x0A
+	tableConstant               = 0x0B
+	tableCustomAttribute        = 0x0C
+	tableFieldMarshal           = 0x0D
+	tableDeclSecurity           = 0x0E
+	tableClassLayout            = 0x0F
+	tableFieldLayout            = 0x10
+	tableStandAloneSig          = 0x11
+	tableEventMap               = 0x12
+	tableEventPtr               = 0x13
+	tableEvent                  = 0x14
+	tablePropertyMap            = 0x15
+	tablePropertyPtr            = 0x16
+	tableProperty               = 0x17
+	tableMethodSemantics        = 0x18
+	tableMethodImpl             = 0x19
+	tableModuleRef              = 0x1A
+	tableTypeSpec               = 0x1B
+	tableImplMap                = 0x1C
+	tableFieldRVA               = 0x1D
+	tableEncLog                 = 0x1E
+	tableEncMap                 = 0x1F
+	tableAssembly               = 0x20
+	tableAssemblyProcessor      = 0x21
+	tableAssemblyOS             = 0x22
+	tableAssemblyRef            = 0x23
+	tableFile                   = 0x26
+	tableExportedType           = 0x27
+	tableManifestResource       = 0x28
+	tableGenericParam           = 0x2A
+	tableMethodSpec             = 0x2B
+	tableGenericParamConstraint = 0x2C
+
+	// Marks unused tags in coded indexes
+	tableUnused = -1
+)
+
+// Column kinds. Non-negative values are fixed-size columns of that many
+// bytes, and the others are heap, table or coded indexes.
+const (
+	colString = -1 - iota
+	colGUID
+	colBlob
+	colTable
+	colCoded
+)
+
+type column struct {
+	kind   int
+	tables []int
+}
+
+func fixed(size int) column {
+	return column{kind: size}
+}
+
+func index(table int) column {
+	return column{kind: colTable, tables: []int{table}}
+}
+
+func coded(tables []int) column {
+	return column{kind: colCoded, tables: tables}
+}
+
+var (
+	str  = column{kind: colString}
+	guid = column{kind: colGUID}
+	blob = column{kind: colBlob}
+)
+
+// Coded index tag tables, from ECMA-335 II.24.2.6
+var (
+	typeDefOrRef        = []int{tableTypeDef, tableTypeRef, tableTypeSpec}
+	hasConstant         = []int{tableField, tableParam, tableProperty}
+	hasCustomAttribute  = []int{tableMethodDef, tableField, tableTypeRef, tableTypeDef, tableParam, tableInterfaceImpl, tableMemberRef, tableModule, tableDeclSecurity, tableProperty, tableEvent, tableStandAloneSig, tableModuleRef, tableTypeSpec, tableAssembly, tableAssemblyRef, tableFile, tableExportedType, tableManifestResource, tableGenericParam, tableGenericParamConstraint, tableMethodSpec}
+	hasFieldMarshal     = []int{tableField, tableParam}
+	hasDeclSecurity     = []int{tableTypeDef, tableMethodDef, tableAssembly}
+	memberRefParent     = []int{tableTypeDef, tableTypeRef, tableModuleRef, tableMethodDef, tableTypeSpec}
+	hasSemantics        = []int{tableEvent, tableProperty}
+	methodDefOrRef      = []int{tableMethodDef, tableMemberRef}
+	memberForwarded     = []int{tableField, tableMethodDef}
+	customAttributeType = []int{tableUnused, tableUnused, tableMethodDef, tableMemberRef, tableUnused}
+	resolutionScope     = []int{tableModule, tableModuleRef, tableAssemblyRef, tableTypeRef}
+)
+
+// schema describes the columns of every table up to and including
+// AssemblyRef. Tables are stored in order, so the sizes of all of these are
+// needed to find where the AssemblyRef table starts.
+var schema = [][]column{
+	tableModule:            {fixed(2), str, guid, guid, guid},
+	tableTypeRef:           {coded(resolutionScope), str, str},
+	tableTypeDef:           {fixed(4), str, str, coded(typeDefOrRef), index(tableField), index(tableMethodDef)},
+	tableFieldPtr:          {index(tableField)},
+	tableField:             {fixed(2), str, blob},
+	tableMethodPtr:         {index(tableMethodDef)},
+	tableMethodDef:         {fixed(4), fixed(2), fixed(2), str, blob, index(tableParam)},
+	tableParamPtr:          {index(tableParam)},
+	tableParam:             {fixed(2), fixed(2), str},
+	tableInterfaceImpl:     {index(tableTypeDef), coded(typeDefOrRef)},
+	tableMemberRef:         {coded(memberRefParent), str, blob},
+	tableConstant:          {fixed(2), coded(hasConstant), blob},
+	tableCustomAttribute:   {coded(hasCustomAttribute), coded(customAttributeType), blob},
+	tableFieldMarshal:      {coded(hasFieldMarshal), blob},
+	tableDeclSecurity:      {fixed(2), coded(hasDeclSecurity), blob},
+	tableClassLayout:       {fixed(2), fixed(4), index(tableTypeDef)},
+	tableFieldLayout:       {fixed(4), index(tableField)},
+	tableStandAloneSig:     {blob},
+	tableEventMap:          {index(tableTypeDef), index(tableEvent)},
+	tableEventPtr:          {index(tableEvent)},
+	tableEvent:             {fixed(2), str, coded(typeDefOrRef)},
+	tablePropertyMap:       {index(tableTypeDef), index(tableProperty)},
+	tablePropertyPtr:       {index(tableProperty)},
+	tableProperty:          {fixed(2), str, blob},
+	tableMethodSemantics:   {fixed(2), index(tableMethodDef), coded(hasSemantics)},
+	tableMethodImpl:        {index(tableTypeDef), coded(methodDefOrRef), coded(methodDefOrRef)},
+	tableModuleRef:         {str},
+	tableTypeSpec:          {blob},
+	tableImplMap:           {fixed(2), coded(memberForwarded), str, index(tableModuleRef)},
+	tableFieldRVA:          {fixed(4), index(tableField)},
+	tableEncLog:            {fixed(4), fixed(4)},
+	tableEncMap:            {fixed(4)},
+	tableAssembly:          {fixed(4), fixed(2), fixed(2), fixed(2), fixed(2), fixed(4), blob, str, str},
+	tableAssemblyProcessor: {fixed(4)},
+	tableAssemblyOS:        {fixed(4), fixed(4), fixed(4)},
+	tableAssemblyRef:       {fixed(2), fixed(2), fixed(2), fixed(2), fixed(4), blob, str, str, blob},
+}
+
+// tables is a parsed #~ metadata stream, along with the heaps its rows
+// index into.
+type tables struct {
+	data      []byte
+	strings   []byte
+	rows      [64]uint32
+	offsets   [64]int
+	heapSizes byte
+}
+
+// section is a PE section header, mapping part of the image into memory.
+type section struct {
+	virtualAddress uint32
+	virtualSize    uint32
+	rawOffset      uint32
+	rawSize        uint32
+}
+
+// Read parses the CLI metadata of a .NET assembly image.
+func Read(image []byte) (*Assembly, error) {
+	sections, cliHeader, err := readPEHeaders(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if cliHeader.VirtualAddress == 0 {
+		return nil, fmt.Errorf("image is not a .NET assembly")
+	}
+
+	cliHeaderData, err := readRVA(sections, image, cliHeader.VirtualAddress, 16)
+	if err != nil {
+		return nil, fmt.Errorf("reading CLI header: %v", err)
+	}
+
+	metadataRVA := binary.LittleEndian.Uint32(cliHeaderData[8:])
+	metadataSize := binary.LittleEndian.Uint32(cliHeaderData[12:])
+	metadata, err := readRVA(sections, image, metadataRVA, metadataSize)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata: %v", err)
+	}
+
+	streams, err := readStreams(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	tableStream, ok := streams["#~"]
+	if !ok {
+		tableStream, ok = streams["#-"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("metadata has no table stream")
+	}
+
+	t, err := readTables(tableStream, streams["#Strings"])
+	if err != nil {
+		return nil, err
+	}
+
+	return t.assembly()
+}
+
+// IsManaged returns true if the provided image is a PE file with a CLI
+// header, which only .NET assemblies have.
+func IsManaged(image []byte) bool {
+	_, cliHeader, err := readPEHeaders(image)
+	return err == nil && cliHeader.VirtualAddress != 0 && cliHeader.Size != 0
+}
+
+// readPEHeaders reads the section table and the CLI header data directory of
+// a PE image. debug/pe isn't used for this, since it rejects the machine
+// types of ReadyToRun images.
+func readPEHeaders(image []byte) ([]*section, pe.DataDirectory, error) {
+	r := &reader{data: image}
+	if string(r.take(2)) != "MZ" {
+		return nil, pe.DataDirectory{}, fmt.Errorf("image is not a PE file")
+	}
+
+	r.pos = 0x3C
+	r.pos = int(r.u32())
+	if string(r.take(4)) != "PE\x00\x00" {
+		return nil, pe.DataDirectory{}, fmt.Errorf("image is not a PE file")
+	}
+
+	// COFF file header
+	r.skip(2)
+	sectionCount := int(r.u16())
+	r.skip(12)
+	optionalHeaderSize := int(r.u16())
+	r.skip(2)
+
+	// Optional header, which has a different layout for 32-bit and 64-bit
+	// images before the data directories
+	optionalHeader := r.pos
+	var dirCountOffset int
+	switch r.u16() {
+	case 0x10b:
+		dirCountOffset = 92
+	case 0x20b:
+		dirCountOffset = 108
+	default:
+		return nil, pe.DataDirectory{}, fmt.Errorf("unknown PE optional header magic")
+	}
+
+	r.pos = optionalHeader + dirCountOffset
+	dirCount := int(r.u32())
+
+	var cliHeader pe.DataDirectory
+	if dirCount > pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR {
+		r.pos += pe.IMAGE_DIRECTORY_ENTRY_COM_DESCRIPTOR * 8
+		cliHeader.VirtualAddress = r.u32()
+		cliHeader.Size = r.u32()
+	}
+
+	r.pos = optionalHeader + optionalHeaderSize
+	sections := make([]*section, 0, sectionCount)
+	for i := 0; i < sectionCount; i++ {
+		r.skip(8)
+		s := &section{}
+		s.virtualSize = r.u32()
+		s.virtualAddress = r.u32()
+		s.rawSize = r.u32()
+		s.rawOffset = r.u32()
+		r.skip(16)
+		sections = append(sections, s)
+	}
+
+	if r.err != nil {
+		return nil, pe.DataDirectory{}, fmt.Errorf("PE headers are truncated")
+	}
+
+	return sections, cliHeader, nil
+}
+
+// readRVA returns the bytes of the image mapped at the provided relative
+// virtual address.
+func readRVA(sections []*section, image []byte, rva, size uint32) ([]byte, error) {
+	for _, s := range sections {
+		if rva < s.virtualAddress || rva >= s.virtualAddress+s.virtualSize {
+			continue
+		}
+
+		start := uint64(s.rawOffset) + uint64(rva-s.virtualAddress)
+		end := start + uint64(size)
+		if end > uint64(len(image)) || end > uint64(s.rawOffset)+uint64(s.rawSize) {
+			return nil, fmt.Errorf("RVA 0x%x is out of bounds", rva)
+		}
+
+		return image[start:end], nil
+	}
+
+	return nil, fmt.Errorf("RVA 0x%x is not in any section", rva)
+}
+
+// readStreams parses the metadata root, returning the streams it contains
+// by name.
+func readStreams(metadata []byte) (map[string][]byte, error) {
+	r := &reader{data: metadata}
+	if r.u32() != 0x424A5342 {
+		return nil, fmt.Errorf("invalid metadata signature")
+	}
+
+	r.skip(8)
+	versionLength := int(r.u32())
+	if r.err != nil || versionLength > len(r.data)-r.pos {
+		return nil, fmt.Errorf("metadata version is out of bounds")
+	}
+
+	r.skip(versionLength)
+	r.skip(2)
+	streamCount := int(r.u16())
+
+	streams := make(map[string][]byte, streamCount)
+	for i := 0; i < streamCount; i++ {
+		offset := r.u32()
+		size := r.u32()
+
+		nameStart := r.pos
+		for r.pos < len(r.data) && r.data[r.pos] != 0 {
+			r.pos++
+		}
+		name := string(r.data[nameStart:r.pos])
+
+		// Names are null-terminated and padded to a 4-byte boundary
+		r.pos = nameStart + (r.pos-nameStart+4)&^3
+		if r.err != nil || r.pos > len(r.data) {
+			return nil, fmt.Errorf("metadata stream headers are truncated")
+		}
+
+		if uint64(offset)+uint64(size) > uint64(len(metadata)) {
+			return nil, fmt.Errorf("metadata stream %s is out of bounds", name)
+		}
+
+		streams[name] = metadata[offset : offset+size]
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return streams, nil
+}
+
+func readTables(data []byte, stringHeap []byte) (*tables, error) {
+	t := &tables{
+		data:    data,
+		strings: stringHeap,
+	}
+
+	r := &reader{data: data}
+	r.skip(6)
+	t.heapSizes = r.u8()
+	r.skip(1)
+	valid := r.u64()
+	r.skip(8)
+
+	for i := 0; i < 64; i++ {
+		if valid&(1<<uint(i)) != 0 {
+			t.rows[i] = r.u32()
+		}
+	}
+
+	// Uncompressed table streams may have an extra field after the row counts
+	if t.heapSizes&0x40 != 0 {
+		r.skip(4)
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("metadata table header is truncated")
+	}
+
+	offset := r.pos
+	for i, cols := range schema {
+		t.offsets[i] = offset
+		offset += t.rowSize(cols) * int(t.rows[i])
+	}
+
+	if offset > len(data) {
+		return nil, fmt.Errorf("metadata tables are truncated")
+	}
+
+	return t, nil
+}
+
+func (t *tables) assembly() (*Assembly, error) {
+	if t.rows[tableAssembly] == 0 {
+		return nil, fmt.Errorf("image has no assembly manifest")
+	}
+
+	row := t.row(tableAssembly, 0)
+	a := &Assembly{
+		Version: Version{
+			Major:    uint16(row[1]),
+			Minor:    uint16(row[2]),
+			Build:    uint16(row[3]),
+			Revision: uint16(row[4]),
+		},
+		Name:       t.readString(row[7]),
+		References: make([]*AssemblyRef, t.rows[tableAssemblyRef]),
+	}
+
+	for i := range a.References {
+		row := t.row(tableAssemblyRef, i)
+		a.References[i] = &AssemblyRef{
+			Version: Version{
+				Major:    uint16(row[0]),
+				Minor:    uint16(row[1]),
+				Build:    uint16(row[2]),
+				Revision: uint16(row[3]),
+			},
+			Name: t.readString(row[6]),
+		}
+	}
+
+	return a, nil
+}
+
+// row reads the values of each column of a row.
+func (t *tables) row(table, n int) []uint32 {
+	cols := schema[table]
+	r := &reader{data: t.data, pos: t.offsets[table] + n*t.rowSize(cols)}
+
+	values := make([]uint32, len(cols))
+	for i, col := range cols {
+		switch t.columnSize(col) {
+		case 1:
+			values[i] = uint32(r.u8())
+		case 2:
+			values[i] = uint32(r.u16())
+		default:
+			values[i] = r.u32()
+		}
+	}
+
+	return values
+}
+
+func (t *tables) rowSize(cols []column) int {
+	size := 0
+	for _, col := range cols {
+		size += t.columnSize(col)
+	}
+
+	return size
+}
+
+func (t *tables) columnSize(col column) int {
+	switch col.kind {
+	case colString:
+		return t.heapIndexSize(0x01)
+	case colGUID:
+		return t.heapIndexSize(0x02)
+	case colBlob:
+		return t.heapIndexSize(0x04)
+	case colTable:
+		if t.rows[col.tables[0]] > 0xFFFF {
+			return 4
+		}
+		return 2
+	case colCoded:
+		// Coded indexes use the low bits to tag which table they refer to,
+		// which leaves less room for the row number
+		tagBits := uint(0)
+		for 1<<tagBits < len(col.tables) {
+			tagBits++
+		}
+
+		maxRows := uint32(0)
+		for _, table := range col.tables {
+			if table != tableUnused && t.rows[table] > maxRows {
+				maxRows = t.rows[table]
+			}
+		}
+
+		if maxRows >= 1<<(16-tagBits) {
+			return 4
+		}
+		return 2
+	default:
+		return col.kind
+	}
+}
+
+func (t *tables) heapIndexSize(flag byte) int {
+	if t.heapSizes&flag != 0 {
+		return 4
+	}
+
+	return 2
+}
+
+func (t *tables) readString(index uint32) string {
+	if int(index) >= len(t.strings) {
+		return ""
+	}
+
+	end := bytes.IndexByte(t.strings[index:], 0)
+	if end == -1 {
+		return string(t.strings[index:])
+	}
+
+	return string(t.strings[index : int(index)+end])
+}
+
+// reader reads little-endian values, recording an error instead of
+// panicking if the data runs out. Reads past the end return zeroes, sized
+// for the largest value read rather than the requested length, since that
+// comes from the image itself.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil || n < 0 || r.pos < 0 || r.pos > len(r.data) || n > len(r.data)-r.pos {
+		r.err = fmt.Errorf("unexpected end of data")
+		return make([]byte, 8)
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.take(n)
+}
+
+func (r *reader) u8() byte {
+	return r.take(1)[0]
+}
+
+func (r *reader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.take(2))
+}
+
+func (r *reader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.take(4))
+}
+
+func (r *reader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.take(8))
+}
@@ -0,0 +1,145 @@
+package dotnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// testdata/TestPlugin.dll is an empty net8.0 class library built with
+// Version set to 1.2.3.4.
+func readTestAssembly(t *testing.T) []byte {
+	t.Helper()
+
+	image, err := os.ReadFile("testdata/TestPlugin.dll")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return image
+}
+
+func TestRead(t *testing.T) {
+	image := readTestAssembly(t)
+
+	if !IsManaged(image) {
+		t.Fatal("expected the test assembly to be managed")
+	}
+
+	a, err := Read(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Name != "TestPlugin" {
+		t.Errorf("expected name TestPlugin, got %s", a.Name)
+	}
+
+	if want := (Version{1, 2, 3, 4}); a.Version != want {
+		t.Errorf("expected version %s, got %s", want, a.Version)
+	}
+
+	runtime := a.Reference("System.Runtime")
+	if runtime == nil {
+		t.Fatal("expected a reference to System.Runtime")
+	}
+
+	if runtime.Version.Major != 8 {
+		t.Errorf("expected System.Runtime 8.x, got %s", runtime.Version)
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	image := readTestAssembly(t)
+
+	// Anything cut off before the metadata root can't be read, and nothing
+	// cut off anywhere should panic
+	root := bytes.Index(image, []byte("BSJB"))
+	for n := 0; n < len(image); n++ {
+		_, err := Read(image[:n])
+		if err == nil && n < root+16 {
+			t.Errorf("expected an error reading %d bytes of the image", n)
+		}
+	}
+}
+
+func TestReadHostileHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch func(image []byte)
+	}{
+		{
+			name: "metadata version length",
+			patch: func(image []byte) {
+				root := bytes.Index(image, []byte("BSJB"))
+				binary.LittleEndian.PutUint32(image[root+12:], 0xF0000000)
+			},
+		},
+		{
+			name: "PE header offset",
+			patch: func(image []byte) {
+				binary.LittleEndian.PutUint32(image[0x3C:], 0xFFFFFFF0)
+			},
+		},
+		{
+			name: "section count",
+			patch: func(image []byte) {
+				peHeader := binary.LittleEndian.Uint32(image[0x3C:])
+				binary.LittleEndian.PutUint16(image[peHeader+6:], 0xFFFF)
+			},
+		},
+		{
+			name: "metadata stream count",
+			patch: func(image []byte) {
+				root := bytes.Index(image, []byte("BSJB"))
+				versionLength := binary.LittleEndian.Uint32(image[root+12:])
+				binary.LittleEndian.PutUint16(image[root+16+int(versionLength)+2:], 0xFFFF)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := readTestAssembly(t)
+			test.patch(image)
+
+			_, err := Read(image)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestReaderTakePastEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  int
+		n    int
+	}{
+		{name: "huge length", pos: 0, n: 0xF0000000},
+		{name: "negative length", pos: 0, n: -1},
+		{name: "position past end", pos: 100, n: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &reader{data: make([]byte, 16), pos: test.pos}
+
+			b := r.take(test.n)
+			if r.err == nil {
+				t.Error("expected an error")
+			}
+
+			if len(b) > 8 {
+				t.Errorf("expected at most 8 bytes, got %d", len(b))
+			}
+
+			// Fixed-size reads still work after an error, returning zero
+			if r.u64() != 0 {
+				t.Error("expected zero after an error")
+			}
+		})
+	}
+}
@@ -2,5 +2,5 @@ package html
 
 import "embed"
 
-//go:embed *.gohtml
+//go:embed *.gohtml *.gotxt
 var Files embed.FS
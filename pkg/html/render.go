@@ -0,0 +1,187 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Body is a rendered email body, containing both the HTML part and its
+// plain-text alternative.
+type Body struct {
+	HTML string
+	Text string
+}
+
+// markdownCellReplacer escapes a value for use in a Markdown table cell,
+// keeping it on a single line and preventing it from injecting markup.
+var markdownCellReplacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"|", "\\|",
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r", "",
+	"\n", "<br>",
+)
+
+var labelColorPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{3}){1,2}$`)
+
+var funcs = map[string]interface{}{
+	"formatTime": func(t time.Time) string {
+		return t.Format(time.RFC822)
+	},
+	"formatDate": func(t time.Time) string {
+		return t.Format("Mon Jan 2")
+	},
+	"formatDuration": FormatDuration,
+	"underline": func(s string, char string) string {
+		return strings.Repeat(char, utf8.RuneCountInString(s))
+	},
+	"markdownCell": func(value interface{}) string {
+		return markdownCellReplacer.Replace(fmt.Sprint(value))
+	}}
+
+var htmlFuncs = map[string]interface{}{
+	// Label colors come from GitHub, so only well-formed hex colors are
+	// allowed into the style attribute.
+	"labelStyle": func(color string) htmltemplate.CSS {
+		if !labelColorPattern.MatchString(color) {
+			return ""
+		}
+
+		return htmltemplate.CSS("color: #" + color + ";")
+	},
+	"ciStyle": func(state fmt.Stringer) htmltemplate.CSS {
+		switch state.String() {
+		case "pass":
+			return "color: #FFF; background-color: #080; padding: 0 4px; border-radius: 3px;"
+		case "fail":
+			return "color: #FFF; background-color: #D00; padding: 0 4px; border-radius: 3px;"
+		default:
+			return "color: #FFF; background-color: #C80; padding: 0 4px; border-radius: 3px;"
+		}
+	},
+	"reviewStyle": func(state fmt.Stringer) htmltemplate.CSS {
+		switch state.String() {
+		case "approved":
+			return "color: #080;"
+		case "changes requested":
+			return "color: #F00;"
+		default:
+			return "color: #888;"
+		}
+	},
+	"severityStyle": func(severity fmt.Stringer) htmltemplate.CSS {
+		switch severity.String() {
+		case "error":
+			return "color: #F00;"
+		case "warning":
+			return "color: #C80;"
+		default:
+			return "color: #888;"
+		}
+	},
+}
+
+// Render executes the template pair <name>.gohtml and <name>.gotxt against
+// data. Any partials are parsed alongside the main template in both formats.
+// The HTML part is rendered with html/template, so all values are escaped
+// according to the context they appear in.
+func Render(data interface{}, name string, partials ...string) (*Body, error) {
+	htmlBody, err := executeHTML(data, name+".gohtml", withExt(partials, ".gohtml"))
+	if err != nil {
+		return nil, err
+	}
+
+	textBody, err := executeText(data, name+".gotxt", withExt(partials, ".gotxt"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Body{
+		HTML: htmlBody,
+		Text: textBody,
+	}, nil
+}
+
+func executeHTML(data interface{}, name string, partials []string) (string, error) {
+	t, err := htmltemplate.New(name).
+		Funcs(htmltemplate.FuncMap(funcs)).
+		Funcs(htmltemplate.FuncMap(htmlFuncs)).
+		ParseFS(Files, append([]string{name}, partials...)...)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func executeText(data interface{}, name string, partials []string) (string, error) {
+	t, err := texttemplate.New(name).
+		Funcs(texttemplate.FuncMap(funcs)).
+		ParseFS(Files, append([]string{name}, partials...)...)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func withExt(names []string, ext string) []string {
+	res := make([]string, len(names))
+	for i, name := range names {
+		res[i] = strings.TrimSuffix(name, ".gohtml") + ext
+	}
+
+	return res
+}
+
+// RenderText executes <name>.gotxt against data on its own, for output that
+// isn't an email, such as pull request comments.
+func RenderText(data interface{}, name string, partials ...string) (string, error) {
+	return executeText(data, name+".gotxt", withExt(partials, ".gotxt"))
+}
+
+// RenderPage executes <name>.gohtml against data as a standalone web page,
+// without a plain-text alternative.
+func RenderPage(w io.Writer, data interface{}, name string) error {
+	t, err := htmltemplate.New(name + ".gohtml").
+		Funcs(htmltemplate.FuncMap(funcs)).
+		Funcs(htmltemplate.FuncMap(htmlFuncs)).
+		ParseFS(Files, name+".gohtml")
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(w, data)
+}
+
+// FormatDuration formats a duration in days and hours, or in hours and
+// minutes if it's shorter than a day.
+func FormatDuration(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, int(d.Hours())%24)
+	}
+
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -9,10 +9,30 @@ import (
 )
 
 type ReaderInfo struct {
+	// ReaderId is set when the reader was identified from a report they
+	// replied to, and is 0 otherwise.
+	ReaderId       int
 	Email          string
 	GitHub         string
 	GitHubSet      bool
 	ReportInterval time.Duration
+	WeeklyStats    bool
+	WeeklyStatsSet bool
+	GreenCIOnly    bool
+	GreenCIOnlySet bool
+	// StaleInterval is how often the reader wants to receive the stale queue
+	// digest, or 0 if they don't.
+	StaleInterval    time.Duration
+	StaleIntervalSet bool
+	// ReportSort and ReportGroup are empty if they weren't provided.
+	ReportSort  string
+	ReportGroup string
+}
+
+// HasDirectives returns whether any of the reader's information was provided.
+func (r *ReaderInfo) HasDirectives() bool {
+	return r.GitHubSet || r.ReportInterval.Minutes() > 0 || r.WeeklyStatsSet || r.GreenCIOnlySet || r.StaleIntervalSet ||
+		r.ReportSort != "" || r.ReportGroup != ""
 }
 
 func ParseBody(email eazye.Email, policy bluemonday.Policy) (*ReaderInfo, error) {
@@ -42,6 +62,52 @@ func ParseBody(email eazye.Email, policy bluemonday.Policy) (*ReaderInfo, error)
 				continue
 			}
 		}
+
+		// Parse whether they want to receive weekly statistics
+		statsMatches := statsPattern.FindStringSubmatch(lineCleaned)
+		if len(statsMatches) != 0 {
+			r.WeeklyStats = strings.EqualFold(statsMatches[statsPattern.SubexpIndex("stats")], "weekly")
+			r.WeeklyStatsSet = true
+			continue
+		}
+
+		// Parse whether they only want pull requests with passing checks
+		ciMatches := ciPattern.FindStringSubmatch(lineCleaned)
+		if len(ciMatches) != 0 {
+			r.GreenCIOnly = strings.EqualFold(ciMatches[ciPattern.SubexpIndex("ci")], "green")
+			r.GreenCIOnlySet = true
+			continue
+		}
+
+		// Parse how often they want to receive the stale queue digest
+		staleMatches := stalePattern.FindStringSubmatch(lineCleaned)
+		if len(staleMatches) != 0 {
+			switch strings.ToLower(staleMatches[stalePattern.SubexpIndex("stale")]) {
+			case "daily":
+				r.StaleInterval = 24 * time.Hour
+			case "weekly":
+				r.StaleInterval = 7 * 24 * time.Hour
+			default:
+				r.StaleInterval = 0
+			}
+
+			r.StaleIntervalSet = true
+			continue
+		}
+
+		// Parse how they want the pull requests in their reports sorted
+		sortMatches := sortPattern.FindStringSubmatch(lineCleaned)
+		if len(sortMatches) != 0 {
+			r.ReportSort = strings.ToLower(sortMatches[sortPattern.SubexpIndex("sort")])
+			continue
+		}
+
+		// Parse how they want the pull requests in their reports grouped
+		groupMatches := groupPattern.FindStringSubmatch(lineCleaned)
+		if len(groupMatches) != 0 {
+			r.ReportGroup = strings.ToLower(groupMatches[groupPattern.SubexpIndex("group")])
+			continue
+		}
 	}
 
 	return r, nil
@@ -4,3 +4,9 @@ import "regexp"
 
 var githubPattern = regexp.MustCompile(`(?i)github:\s*(?P<github>\S*)`)
 var intervalPattern = regexp.MustCompile(`(?i)interval:\s*(?P<interval>\S*)`)
+var statsPattern = regexp.MustCompile(`(?i)stats:\s*(?P<stats>weekly|off)\b`)
+var ciPattern = regexp.MustCompile(`(?i)ci:\s*(?P<ci>green|all)\b`)
+var stalePattern = regexp.MustCompile(`(?i)stale:\s*(?P<stale>daily|weekly|off)\b`)
+var sortPattern = regexp.MustCompile(`(?i)sort:\s*(?P<sort>updated|created|problems|title)\b`)
+var groupPattern = regexp.MustCompile(`(?i)group:\s*(?P<group>none|label|validation|channel)\b`)
+var replyPrefixPattern = regexp.MustCompile(`^(?i)(?:re:\s*)+`)
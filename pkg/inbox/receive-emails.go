@@ -8,6 +8,8 @@ import (
 
 	"github.com/jackc/pgx"
 	"github.com/jprobinson/eazye"
+	"github.com/karashiiro/operator/pkg/threading"
+	"github.com/karashiiro/operator/pkg/unsubscribe"
 	"github.com/microcosm-cc/bluemonday"
 )
 
@@ -33,10 +35,18 @@ func (j *ReceiveEmailsJob) Execute() {
 	newReaders := make([]*ReaderInfo, 0)
 	updatedReaders := make([]*ReaderInfo, 0)
 	unsubscribers := make([]string, 0)
+	tokenUnsubscribers := make([]int, 0)
 	for _, email := range emails {
 		// Parse out the email information
 		subjectCleaned := strings.TrimSpace(email.Subject)
 
+		// Replies to a report are matched back to the reader it was sent to
+		report, isReply := findRepliedReport(email)
+		if isReply {
+			log.Printf("Found reply to report %d from reader %d\n", report.ReportId, report.ReaderId)
+			subjectCleaned = replyPrefixPattern.ReplaceAllString(subjectCleaned, "")
+		}
+
 		if strings.HasPrefix(subjectCleaned, "[op] subscribe") {
 			r, err := ParseBody(email, *j.Policy)
 			if err != nil {
@@ -61,13 +71,46 @@ func (j *ReceiveEmailsJob) Execute() {
 
 			log.Println("Found new information update email, adding to list")
 			updatedReaders = append(updatedReaders, r)
-		} else if strings.HasPrefix(subjectCleaned, "[op] unsubscribe") {
+		} else if strings.HasPrefix(subjectCleaned, unsubscribe.SubjectPrefix) {
+			// Unsubscribe requests sent through the List-Unsubscribe header carry
+			// a signed token identifying the reader. If it can't be verified, such
+			// as when no secret is configured, the sender is unsubscribed instead.
+			token := unsubscribe.ParseSubject(subjectCleaned)
+			if token != "" {
+				readerId, err := unsubscribe.Verify(token)
+				if err == nil {
+					log.Println("Found new token unsubscribe email, adding to list")
+					tokenUnsubscribers = append(tokenUnsubscribers, readerId)
+					continue
+				}
+
+				log.Printf("Unable to verify unsubscribe token, unsubscribing sender instead: %v\n", err)
+			}
+
 			log.Println("Found new unsubscribe email, adding to list")
 			unsubscribers = append(unsubscribers, email.From.Address)
+		} else if isReply {
+			// Replying to a report with directives in the body updates the
+			// reader's information, as long as it comes from their address
+			r, err := ParseBody(email, *j.Policy)
+			if err != nil {
+				log.Printf("Failed to parse report reply: %v\n", err)
+				continue
+			}
+
+			if !r.HasDirectives() {
+				log.Println("Report reply has no directives, ignoring")
+				continue
+			}
+
+			r.ReaderId = report.ReaderId
+
+			log.Println("Found new information update in report reply, adding to list")
+			updatedReaders = append(updatedReaders, r)
 		}
 	}
 
-	if len(newReaders) == 0 && len(updatedReaders) == 0 && len(unsubscribers) == 0 {
+	if len(newReaders) == 0 && len(updatedReaders) == 0 && len(unsubscribers) == 0 && len(tokenUnsubscribers) == 0 {
 		log.Println("No unread operator emails found")
 		return
 	}
@@ -96,6 +139,11 @@ func (j *ReceiveEmailsJob) Execute() {
 		log.Println("Processing unsubscribers")
 		deleteUnsubscribers(readerConn, unsubscribers)
 	}
+
+	if len(tokenUnsubscribers) > 0 {
+		log.Println("Processing token unsubscribers")
+		deleteTokenUnsubscribers(readerConn, tokenUnsubscribers)
+	}
 }
 
 func (j *ReceiveEmailsJob) Description() string {
@@ -113,6 +161,14 @@ func (j *ReceiveEmailsJob) Key() int {
 	return int(h.Sum32())
 }
 
+func findRepliedReport(email eazye.Email) (*threading.ReportRef, bool) {
+	if email.Message == nil {
+		return nil, false
+	}
+
+	return threading.FindReport(email.Message.Header.Get("In-Reply-To"), email.Message.Header.Get("References"))
+}
+
 func getEmails(mailbox string) ([]eazye.Email, error) {
 	auth := eazye.MailboxInfo{
 		Host:   os.Getenv("OPERATOR_IMAP_SERVER"),
@@ -1,10 +1,7 @@
 package inbox
 
 import (
-	"bytes"
-	"io"
 	"log"
-	"text/template"
 	"time"
 
 	"github.com/jackc/pgx"
@@ -22,13 +19,13 @@ func saveSubscribers(conn *pgx.Conn, readers []*ReaderInfo) {
 
 		log.Printf("Sending subscription confirmation email to %s\n", r.Email)
 
-		var subscribeMessage bytes.Buffer
-		err = buildSubscribeTemplate(&subscribeMessage, r.ReportInterval)
+		subscribeMessage, err := buildSubscribeTemplate(r.ReportInterval, r.WeeklyStats)
 		if err != nil {
 			log.Printf("Failed to build subscribe template: %v\n", err)
+			continue
 		}
 
-		err = outlook.SendEmail(r.Email, "Subscription confirmed", subscribeMessage.String())
+		err = outlook.SendEmail(r.Email, "Subscription confirmed", subscribeMessage.HTML, subscribeMessage.Text)
 		if err != nil {
 			log.Printf("Unable to send mail: %v\n", err)
 			continue
@@ -38,30 +35,28 @@ func saveSubscribers(conn *pgx.Conn, readers []*ReaderInfo) {
 	}
 }
 
-func buildSubscribeTemplate(w io.Writer, interval time.Duration) error {
-	t, err := template.ParseFS(html.Files, "confirm-subscribe.gohtml")
-	if err != nil {
-		return err
-	}
-
-	err = t.Execute(w, struct {
-		Interval time.Duration
+func buildSubscribeTemplate(interval time.Duration, weeklyStats bool) (*html.Body, error) {
+	return html.Render(struct {
+		Interval    time.Duration
+		WeeklyStats bool
 	}{
-		Interval: interval,
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
+		Interval:    interval,
+		WeeklyStats: weeklyStats,
+	}, "confirm-subscribe")
 }
 
 func storeReader(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	var staleInterval *time.Duration
+	if r.StaleInterval > 0 {
+		staleInterval = &r.StaleInterval
+	}
+
 	t, err := conn.Exec(`
-		INSERT INTO Reader (email, github, report_interval, active)
+		INSERT INTO Reader (email, github, report_interval, active, weekly_stats, green_ci_only, stale_interval,
+			report_sort, report_group)
 		VALUES
-			($1, $2, $3, TRUE)
-	`, r.Email, r.GitHub, r.ReportInterval)
+			($1, $2, $3, TRUE, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+	`, r.Email, r.GitHub, r.ReportInterval, r.WeeklyStats, r.GreenCIOnly, staleInterval, r.ReportSort, r.ReportGroup)
 	if err != nil {
 		return 0, err
 	}
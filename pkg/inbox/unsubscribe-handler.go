@@ -0,0 +1,79 @@
+package inbox
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/unsubscribe"
+)
+
+// UnsubscribeHandler serves the one-click unsubscribe URL advertised in the
+// List-Unsubscribe header. GET requests show a confirmation form so that
+// link scanners don't unsubscribe anyone; POST requests unsubscribe the
+// reader the token was issued for.
+type UnsubscribeHandler struct {
+	Pool *pgx.ConnPool
+}
+
+type unsubscribePage struct {
+	Action  string
+	Done    bool
+	Invalid bool
+}
+
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	readerId, err := unsubscribe.Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		log.Printf("Rejected unsubscribe request: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		writeUnsubscribePage(w, &unsubscribePage{Invalid: true})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeUnsubscribePage(w, &unsubscribePage{Action: r.URL.RequestURI()})
+	case http.MethodPost:
+		conn, err := h.Pool.Acquire()
+		if err != nil {
+			log.Printf("Failed to acquire database connection: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer h.Pool.Release(conn)
+
+		addr, err := deleteReaderById(conn, readerId)
+		if err == pgx.ErrNoRows {
+			// The reader already unsubscribed, so there's nothing left to do
+			writeUnsubscribePage(w, &unsubscribePage{Done: true})
+			return
+		} else if err != nil {
+			log.Printf("Failed to delete reader: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Deleted reader %s\n", addr)
+
+		err = sendUnsubscribeConfirmation(addr)
+		if err != nil {
+			log.Printf("Unable to send mail: %v\n", err)
+		}
+
+		writeUnsubscribePage(w, &unsubscribePage{Done: true})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeUnsubscribePage(w http.ResponseWriter, page *unsubscribePage) {
+	err := html.RenderPage(w, page, "unsubscribe-page")
+	if err != nil {
+		log.Printf("Failed to build unsubscribe page: %v\n", err)
+	}
+}
@@ -1,10 +1,7 @@
 package inbox
 
 import (
-	"bytes"
-	"io"
 	"log"
-	"text/template"
 
 	"github.com/jackc/pgx"
 	"github.com/karashiiro/operator/pkg/html"
@@ -19,24 +16,49 @@ func deleteUnsubscribers(conn *pgx.Conn, unsubscribers []string) {
 			continue
 		}
 
-		var unsubscribeMessage bytes.Buffer
-		err = buildUnsubscribeTemplate(&unsubscribeMessage)
+		err = sendUnsubscribeConfirmation(us)
 		if err != nil {
-			log.Printf("Failed to build unsubscribe template: %v\n", err)
+			log.Printf("Unable to send mail: %v\n", err)
+			continue
+		}
+
+		log.Printf("Deleted reader %s\n", us)
+	}
+}
+
+func deleteTokenUnsubscribers(conn *pgx.Conn, readerIds []int) {
+	for _, readerId := range readerIds {
+		addr, err := deleteReaderById(conn, readerId)
+		if err != nil {
+			log.Printf("Failed to delete reader: %v\n", err)
 			continue
 		}
 
-		err = outlook.SendEmail(us, "Unsubscribe confirmed", unsubscribeMessage.String())
+		err = sendUnsubscribeConfirmation(addr)
 		if err != nil {
 			log.Printf("Unable to send mail: %v\n", err)
 			continue
 		}
 
-		log.Printf("Deleted reader %s\n", us)
+		log.Printf("Deleted reader %s\n", addr)
+	}
+}
+
+func sendUnsubscribeConfirmation(addr string) error {
+	unsubscribeMessage, err := buildUnsubscribeTemplate()
+	if err != nil {
+		return err
 	}
+
+	return outlook.SendEmail(addr, "Unsubscribe confirmed", unsubscribeMessage.HTML, unsubscribeMessage.Text)
 }
 
 func deleteReader(conn *pgx.Conn, addr string) (int64, error) {
+	_, err := conn.Exec("DELETE FROM ReportSnapshot WHERE reader_id = (SELECT id FROM Reader WHERE email = $1);", addr)
+	if err != nil {
+		return 0, err
+	}
+
 	t1, err := conn.Exec("DELETE FROM Report WHERE reader_id = (SELECT id FROM Reader WHERE email = $1);", addr)
 	if err != nil {
 		return 0, err
@@ -50,16 +72,28 @@ func deleteReader(conn *pgx.Conn, addr string) (int64, error) {
 	return t1.RowsAffected() + t2.RowsAffected(), nil
 }
 
-func buildUnsubscribeTemplate(w io.Writer) error {
-	t, err := template.ParseFS(html.Files, "confirm-unsubscribe.gohtml")
+// deleteReaderById deletes a reader and their report history, returning the
+// email address the reader was subscribed with.
+func deleteReaderById(conn *pgx.Conn, readerId int) (string, error) {
+	_, err := conn.Exec("DELETE FROM ReportSnapshot WHERE reader_id = $1;", readerId)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = t.Execute(w, struct{}{})
+	_, err = conn.Exec("DELETE FROM Report WHERE reader_id = $1;", readerId)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	var addr string
+	err = conn.QueryRow("DELETE FROM Reader WHERE id = $1 RETURNING email;", readerId).Scan(&addr)
+	if err != nil {
+		return "", err
+	}
+
+	return addr, nil
+}
+
+func buildUnsubscribeTemplate() (*html.Body, error) {
+	return html.Render(struct{}{}, "confirm-unsubscribe")
 }
@@ -1,10 +1,7 @@
 package inbox
 
 import (
-	"bytes"
-	"io"
 	"log"
-	"text/template"
 	"time"
 
 	"github.com/jackc/pgx"
@@ -15,7 +12,7 @@ import (
 func saveUpdatedInfo(conn *pgx.Conn, readers []*ReaderInfo) {
 	for _, r := range readers {
 		if r.GitHubSet {
-			_, err := updateGitHub(conn, r.GitHub)
+			_, err := updateGitHub(conn, r)
 			if err != nil {
 				log.Printf("Failed to update reader GitHub: %v\n", err)
 				continue
@@ -23,22 +20,62 @@ func saveUpdatedInfo(conn *pgx.Conn, readers []*ReaderInfo) {
 		}
 
 		if r.ReportInterval.Minutes() > 0 {
-			_, err := updateReportInterval(conn, r.ReportInterval)
+			_, err := updateReportInterval(conn, r)
 			if err != nil {
 				log.Printf("Failed to update reader report interval: %v\n", err)
 				continue
 			}
 		}
 
+		if r.WeeklyStatsSet {
+			_, err := updateWeeklyStats(conn, r)
+			if err != nil {
+				log.Printf("Failed to update reader weekly statistics: %v\n", err)
+				continue
+			}
+		}
+
+		if r.GreenCIOnlySet {
+			_, err := updateGreenCIOnly(conn, r)
+			if err != nil {
+				log.Printf("Failed to update reader CI filter: %v\n", err)
+				continue
+			}
+		}
+
+		if r.StaleIntervalSet {
+			_, err := updateStaleInterval(conn, r)
+			if err != nil {
+				log.Printf("Failed to update reader stale digest interval: %v\n", err)
+				continue
+			}
+		}
+
+		if r.ReportSort != "" {
+			_, err := updateReportSort(conn, r)
+			if err != nil {
+				log.Printf("Failed to update reader report sort order: %v\n", err)
+				continue
+			}
+		}
+
+		if r.ReportGroup != "" {
+			_, err := updateReportGroup(conn, r)
+			if err != nil {
+				log.Printf("Failed to update reader report grouping: %v\n", err)
+				continue
+			}
+		}
+
 		log.Printf("Sending update confirmation email to %s\n", r.Email)
 
-		var updateMessage bytes.Buffer
-		err := buildUpdateTemplate(&updateMessage, r.ReportInterval)
+		updateMessage, err := buildUpdateTemplate(r.ReportInterval)
 		if err != nil {
 			log.Printf("Failed to build update template: %v\n", err)
+			continue
 		}
 
-		err = outlook.SendEmail(r.Email, "Information updated", updateMessage.String())
+		err = outlook.SendEmail(r.Email, "Information updated", updateMessage.HTML, updateMessage.Text)
 		if err != nil {
 			log.Printf("Unable to send mail: %v\n", err)
 			continue
@@ -48,33 +85,89 @@ func saveUpdatedInfo(conn *pgx.Conn, readers []*ReaderInfo) {
 	}
 }
 
-func buildUpdateTemplate(w io.Writer, interval time.Duration) error {
-	t, err := template.ParseFS(html.Files, "confirm-update.gohtml")
-	if err != nil {
-		return err
-	}
-
-	err = t.Execute(w, struct {
+func buildUpdateTemplate(interval time.Duration) (*html.Body, error) {
+	return html.Render(struct {
 		Interval time.Duration
 	}{
 		Interval: interval,
-	})
+	}, "confirm-update")
+}
+
+func updateGitHub(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	var github *string
+	if r.GitHub != "" {
+		github = &r.GitHub
+	}
+
+	t, err := conn.Exec(`
+		UPDATE Reader SET github = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, github, r.Email, r.ReaderId)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return t.RowsAffected(), nil
 }
 
-func updateGitHub(conn *pgx.Conn, gh string) (int64, error) {
-	var github *string
-	if gh != "" {
-		github = &gh
+func updateReportInterval(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	t, err := conn.Exec(`
+		UPDATE Reader SET report_interval = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, r.ReportInterval, r.Email, r.ReaderId)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.RowsAffected(), nil
+}
+
+func updateWeeklyStats(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	t, err := conn.Exec(`
+		UPDATE Reader SET weekly_stats = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, r.WeeklyStats, r.Email, r.ReaderId)
+	if err != nil {
+		return 0, err
 	}
 
+	return t.RowsAffected(), nil
+}
+
+func updateGreenCIOnly(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	t, err := conn.Exec(`
+		UPDATE Reader SET green_ci_only = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, r.GreenCIOnly, r.Email, r.ReaderId)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.RowsAffected(), nil
+}
+
+func updateStaleInterval(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
+	var interval *time.Duration
+	if r.StaleInterval > 0 {
+		interval = &r.StaleInterval
+	}
+
+	t, err := conn.Exec(`
+		UPDATE Reader SET stale_interval = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, interval, r.Email, r.ReaderId)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.RowsAffected(), nil
+}
+
+func updateReportSort(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
 	t, err := conn.Exec(`
-		UPDATE Reader SET github = $1;
-	`, github)
+		UPDATE Reader SET report_sort = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, r.ReportSort, r.Email, r.ReaderId)
 	if err != nil {
 		return 0, err
 	}
@@ -82,10 +175,11 @@ func updateGitHub(conn *pgx.Conn, gh string) (int64, error) {
 	return t.RowsAffected(), nil
 }
 
-func updateReportInterval(conn *pgx.Conn, interval time.Duration) (int64, error) {
+func updateReportGroup(conn *pgx.Conn, r *ReaderInfo) (int64, error) {
 	t, err := conn.Exec(`
-		UPDATE Reader SET report_interval = $1;
-	`, interval)
+		UPDATE Reader SET report_group = $1
+		WHERE email = $2 AND ($3::INTEGER = 0 OR id = $3::INTEGER);
+	`, r.ReportGroup, r.Email, r.ReaderId)
 	if err != nil {
 		return 0, err
 	}
@@ -2,18 +2,43 @@ package outlook
 
 import (
 	"fmt"
+	"net/textproto"
 	"os"
 
 	"github.com/jordan-wright/email"
 )
 
-func SendEmail(to, subject, body string) error {
+// Message is an outgoing email. Headers are sent in addition to the ones
+// derived from the other fields.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	Headers textproto.MIMEHeader
+}
+
+func SendEmail(to, subject, htmlBody, textBody string) error {
+	return Send(&Message{
+		To:      to,
+		Subject: subject,
+		HTML:    htmlBody,
+		Text:    textBody,
+	})
+}
+
+func Send(m *Message) error {
 	auth := LoginAuth(os.Getenv("OPERATOR_EMAIL"), os.Getenv("OPERATOR_PASSWORD"))
 	e := email.NewEmail()
-	e.To = []string{to}
+	e.To = []string{m.To}
 	e.From = fmt.Sprintf("Caprine Operator <%s>", os.Getenv("OPERATOR_EMAIL"))
-	e.Subject = subject
-	e.HTML = []byte(body)
+	e.Subject = m.Subject
+	e.HTML = []byte(m.HTML)
+	e.Text = []byte(m.Text)
+
+	for k, v := range m.Headers {
+		e.Headers[k] = v
+	}
 
 	err := e.Send(os.Getenv("OPERATOR_SMTP_SERVER"), auth)
 	if err != nil {
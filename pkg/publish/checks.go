@@ -0,0 +1,179 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+const (
+	// checkName is the name of the check run and the context of the commit
+	// status shown in the pull request's checks
+	checkName = plogons.ValidationCheck
+
+	// maxAnnotations is the number of annotations GitHub accepts per check
+	// run request
+	maxAnnotations = 50
+)
+
+// Check modes
+const (
+	ChecksNone     = ""
+	ChecksStatus   = "status"
+	ChecksCheckRun = "check-run"
+)
+
+// ChecksFromEnv returns the check mode configured in OPERATOR_GITHUB_CHECKS,
+// failing if it isn't one of the known modes.
+func ChecksFromEnv() (string, error) {
+	mode := os.Getenv("OPERATOR_GITHUB_CHECKS")
+	switch mode {
+	case ChecksNone, ChecksStatus, ChecksCheckRun:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown check mode %q, expected %q or %q", mode, ChecksStatus, ChecksCheckRun)
+	}
+}
+
+// summarize describes the outcome of validating a pull request in a single
+// short line.
+func summarize(result *plogons.PullRequestValidationResult, validationErr error) string {
+	if validationErr != nil {
+		return "The pull request could not be validated"
+	}
+
+	return result.Summary()
+}
+
+func passed(result *plogons.PullRequestValidationResult, validationErr error) bool {
+	return validationErr == nil && !result.HasErrors()
+}
+
+// createStatus sets a commit status on the pull request's head commit.
+func createStatus(ctx context.Context, client *github.Client, pr *github.PullRequest, result *plogons.PullRequestValidationResult, validationErr error) error {
+	state := "success"
+	if validationErr != nil {
+		state = "error"
+	} else if !passed(result, validationErr) {
+		state = "failure"
+	}
+
+	description := summarize(result, validationErr)
+	if len(description) > 140 {
+		description = description[:137] + "..."
+	}
+
+	owner, repo := repoName(pr)
+	_, _, err := client.Repositories.CreateStatus(ctx, owner, repo, pr.GetHead().GetSHA(), &github.RepoStatus{
+		State:       github.String(state),
+		Description: github.String(description),
+		Context:     github.String(checkName),
+	})
+
+	return err
+}
+
+// annotations creates an annotation for every finding. Findings without a
+// file are attached to their plugin's manifest. GitHub requires a line
+// range, and findings aren't tied to a line, so the first line is used.
+func annotations(result *plogons.PullRequestValidationResult) []*github.CheckRunAnnotation {
+	res := make([]*github.CheckRunAnnotation, 0)
+	if result == nil {
+		return res
+	}
+
+	for _, p := range result.Plugins {
+		for _, f := range p.Findings {
+			file := f.File
+			if file == "" {
+				file = path.Join(p.Dir(), p.Name+".json")
+			}
+
+			level := "notice"
+			switch f.Severity {
+			case plogons.SeverityError:
+				level = "failure"
+			case plogons.SeverityWarning:
+				level = "warning"
+			}
+
+			title := f.ID
+			if f.Field != "" {
+				title += " (" + f.Field + ")"
+			}
+
+			res = append(res, &github.CheckRunAnnotation{
+				Path:            github.String(file),
+				StartLine:       github.Int(1),
+				EndLine:         github.Int(1),
+				AnnotationLevel: github.String(level),
+				Message:         github.String(f.Message),
+				Title:           github.String(title),
+			})
+		}
+	}
+
+	return res
+}
+
+// createCheckRun creates a completed check run on the pull request's head
+// commit. GitHub only accepts a limited number of annotations per request,
+// so any remaining annotations are added by updating the check run.
+func createCheckRun(ctx context.Context, client *github.Client, pr *github.PullRequest, result *plogons.PullRequestValidationResult, validationErr error, summary string) error {
+	conclusion := "success"
+	if !passed(result, validationErr) {
+		conclusion = "failure"
+	}
+
+	title := summarize(result, validationErr)
+	all := annotations(result)
+	batch := func() []*github.CheckRunAnnotation {
+		n := len(all)
+		if n > maxAnnotations {
+			n = maxAnnotations
+		}
+
+		b := all[:n]
+		all = all[n:]
+		return b
+	}
+
+	owner, repo := repoName(pr)
+	now := github.Timestamp{Time: time.Now()}
+	checkRun, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, github.CreateCheckRunOptions{
+		Name:        checkName,
+		HeadSHA:     pr.GetHead().GetSHA(),
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &now,
+		Output: &github.CheckRunOutput{
+			Title:       github.String(title),
+			Summary:     github.String(summary),
+			Annotations: batch(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for len(all) > 0 {
+		_, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, checkRun.GetID(), github.UpdateCheckRunOptions{
+			Name: checkName,
+			Output: &github.CheckRunOutput{
+				Title:       github.String(title),
+				Summary:     github.String(summary),
+				Annotations: batch(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
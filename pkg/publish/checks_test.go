@@ -0,0 +1,308 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+const testHeadSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// recordedRequest is a request made to the fake GitHub API.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// newTestClient creates a GitHub client whose requests are recorded and
+// answered by a local server. Responses echo an ID, which is all the
+// callers under test read back.
+func newTestClient(t *testing.T) (*github.Client, func() []*recordedRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := make([]*recordedRequest, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &recordedRequest{Method: r.Method, Path: r.URL.Path}
+		err := json.NewDecoder(r.Body).Decode(&req.Body)
+		if err != nil {
+			t.Errorf("unable to decode request body: %v", err)
+		}
+
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+
+	return client, func() []*recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func testPullRequest() *github.PullRequest {
+	return &github.PullRequest{
+		Number: github.Int(1),
+		Head: &github.PullRequestBranch{
+			SHA: github.String(testHeadSHA),
+		},
+		Base: &github.PullRequestBranch{
+			Repo: &github.Repository{
+				Name:  github.String("DalamudPlugins"),
+				Owner: &github.User{Login: github.String("goatcorp")},
+			},
+		},
+	}
+}
+
+// testResult creates a validation result for a single plugin with the
+// provided number of error and warning findings.
+func testResult(errors, warnings int) *plogons.PullRequestValidationResult {
+	findings := make([]*plogons.Finding, 0, errors+warnings)
+	for i := 0; i < errors; i++ {
+		findings = append(findings, &plogons.Finding{
+			ID:       "meta.required",
+			Severity: plogons.SeverityError,
+			Message:  fmt.Sprintf("Error %d", i),
+		})
+	}
+
+	for i := 0; i < warnings; i++ {
+		findings = append(findings, &plogons.Finding{
+			ID:       "schema.length",
+			Severity: plogons.SeverityWarning,
+			Message:  fmt.Sprintf("Warning %d", i),
+			Field:    "Description",
+			File:     "testing/TestPlugin/TestPlugin.json",
+		})
+	}
+
+	return &plogons.PullRequestValidationResult{
+		Plugins: []*plogons.PlogonMetaValidationResult{{
+			Channel:  "testing",
+			Name:     "TestPlugin",
+			Findings: findings,
+		}},
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        *plogons.PullRequestValidationResult
+		validationErr error
+		state         string
+	}{
+		{name: "passed", result: testResult(0, 0), state: "success"},
+		{name: "warnings only", result: testResult(0, 2), state: "success"},
+		{name: "errors", result: testResult(1, 2), state: "failure"},
+		{name: "validation error", validationErr: errors.New("rate limited"), state: "error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newTestClient(t)
+
+			err := createStatus(context.Background(), client, testPullRequest(), test.result, test.validationErr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reqs := requests()
+			if len(reqs) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(reqs))
+			}
+
+			req := reqs[0]
+			if want := "/repos/goatcorp/DalamudPlugins/statuses/" + testHeadSHA; req.Method != http.MethodPost || req.Path != want {
+				t.Errorf("expected POST %s, got %s %s", want, req.Method, req.Path)
+			}
+
+			if req.Body["state"] != test.state {
+				t.Errorf("expected state %s, got %v", test.state, req.Body["state"])
+			}
+
+			if req.Body["context"] != checkName {
+				t.Errorf("expected context %s, got %v", checkName, req.Body["context"])
+			}
+
+			if want := summarize(test.result, test.validationErr); req.Body["description"] != want {
+				t.Errorf("expected description %q, got %v", want, req.Body["description"])
+			}
+		})
+	}
+}
+
+func TestCreateStatusTruncatesDescription(t *testing.T) {
+	client, requests := newTestClient(t)
+
+	result := testResult(0, 0)
+	for i := 0; i < 20; i++ {
+		result.Plugins = append(result.Plugins, &plogons.PlogonMetaValidationResult{
+			Channel: "testing",
+			Name:    fmt.Sprintf("BrokenPlugin%d", i),
+			Err:     errors.New("not found"),
+		})
+	}
+
+	err := createStatus(context.Background(), client, testPullRequest(), result, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description, _ := requests()[0].Body["description"].(string)
+	if len(description) != 140 || !strings.HasSuffix(description, "...") {
+		t.Errorf("expected a 140 character description ending in an ellipsis, got %q", description)
+	}
+}
+
+func TestCreateCheckRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		result        *plogons.PullRequestValidationResult
+		validationErr error
+		conclusion    string
+
+		// batches is the number of annotations expected in each request
+		batches []int
+	}{
+		{name: "passed", result: testResult(0, 0), conclusion: "success", batches: []int{0}},
+		{name: "warnings only", result: testResult(0, 3), conclusion: "success", batches: []int{3}},
+		{name: "errors", result: testResult(2, 3), conclusion: "failure", batches: []int{5}},
+		{name: "validation error", validationErr: errors.New("rate limited"), conclusion: "failure", batches: []int{0}},
+		{name: "exactly one batch", result: testResult(50, 0), conclusion: "failure", batches: []int{50}},
+		{name: "several batches", result: testResult(70, 50), conclusion: "failure", batches: []int{50, 50, 20}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newTestClient(t)
+
+			err := createCheckRun(context.Background(), client, testPullRequest(), test.result, test.validationErr, "summary")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reqs := requests()
+			if len(reqs) != len(test.batches) {
+				t.Fatalf("expected %d requests, got %d", len(test.batches), len(reqs))
+			}
+
+			create := reqs[0]
+			if want := "/repos/goatcorp/DalamudPlugins/check-runs"; create.Method != http.MethodPost || create.Path != want {
+				t.Errorf("expected POST %s, got %s %s", want, create.Method, create.Path)
+			}
+
+			if create.Body["name"] != checkName {
+				t.Errorf("expected name %s, got %v", checkName, create.Body["name"])
+			}
+
+			if create.Body["head_sha"] != testHeadSHA {
+				t.Errorf("expected head SHA %s, got %v", testHeadSHA, create.Body["head_sha"])
+			}
+
+			if create.Body["status"] != "completed" || create.Body["conclusion"] != test.conclusion {
+				t.Errorf("expected completed with %s, got %v with %v", test.conclusion, create.Body["status"], create.Body["conclusion"])
+			}
+
+			for i, req := range reqs[1:] {
+				if want := "/repos/goatcorp/DalamudPlugins/check-runs/1"; req.Method != http.MethodPatch || req.Path != want {
+					t.Errorf("expected update %d to be PATCH %s, got %s %s", i, want, req.Method, req.Path)
+				}
+			}
+
+			for i, req := range reqs {
+				output, _ := req.Body["output"].(map[string]interface{})
+				if want := summarize(test.result, test.validationErr); output["title"] != want {
+					t.Errorf("expected request %d to have title %q, got %v", i, want, output["title"])
+				}
+
+				annotations, _ := output["annotations"].([]interface{})
+				if len(annotations) != test.batches[i] {
+					t.Errorf("expected request %d to have %d annotations, got %d", i, test.batches[i], len(annotations))
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRunAnnotations(t *testing.T) {
+	client, requests := newTestClient(t)
+
+	err := createCheckRun(context.Background(), client, testPullRequest(), testResult(1, 1), nil, "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, _ := requests()[0].Body["output"].(map[string]interface{})
+	annotations, _ := output["annotations"].([]interface{})
+	if len(annotations) != 2 {
+		t.Fatalf("expected 2 annotations, got %d", len(annotations))
+	}
+
+	tests := []struct {
+		path  string
+		level string
+		title string
+	}{
+		// Findings without a file are attached to the plugin's manifest
+		{path: "testing/TestPlugin/TestPlugin.json", level: "failure", title: "meta.required"},
+		{path: "testing/TestPlugin/TestPlugin.json", level: "warning", title: "schema.length (Description)"},
+	}
+
+	for i, test := range tests {
+		annotation, _ := annotations[i].(map[string]interface{})
+		if annotation["path"] != test.path || annotation["annotation_level"] != test.level || annotation["title"] != test.title {
+			t.Errorf("expected annotation %d to be %s %s %s, got %v", i, test.path, test.level, test.title, annotation)
+		}
+	}
+}
+
+func TestChecksFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "", valid: true},
+		{value: "status", valid: true},
+		{value: "check-run", valid: true},
+		{value: "checks", valid: false},
+		{value: "Status", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			t.Setenv("OPERATOR_GITHUB_CHECKS", test.value)
+
+			mode, err := ChecksFromEnv()
+			if test.valid && (err != nil || mode != test.value) {
+				t.Errorf("expected %q to be accepted, got %q and %v", test.value, mode, err)
+			} else if !test.valid && err == nil {
+				t.Errorf("expected %q to be rejected", test.value)
+			}
+		})
+	}
+}
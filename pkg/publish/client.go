@@ -0,0 +1,44 @@
+package publish
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v44/github"
+)
+
+// tokenTransport authenticates GitHub API requests with a personal access
+// token or app installation token.
+type tokenTransport struct {
+	Token string
+	Base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the original request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+t.Token)
+	return t.Base.RoundTrip(req)
+}
+
+// NewClient creates a GitHub client authenticated with the token in
+// OPERATOR_GITHUB_TOKEN. It returns nil if no token is configured.
+func NewClient() *github.Client {
+	token := os.Getenv("OPERATOR_GITHUB_TOKEN")
+	if token == "" {
+		return nil
+	}
+
+	return github.NewClient(&http.Client{
+		Transport: &tokenTransport{
+			Token: token,
+			Base:  http.DefaultTransport,
+		},
+	})
+}
+
+// DryRun returns true if changes to pull requests should only be logged
+// instead of being made.
+func DryRun() bool {
+	return os.Getenv("OPERATOR_GITHUB_DRY_RUN") == "true"
+}
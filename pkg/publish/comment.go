@@ -0,0 +1,108 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// commentMarker identifies the Operator's validation comment on a pull
+// request. It's rendered as an HTML comment, so it isn't visible on GitHub.
+const commentMarker = "<!-- operator:validation -->"
+
+// commentTemplate is the content of the validation comment. The comment is
+// only updated when the findings change, so it doesn't include anything
+// specific to the head commit.
+type commentTemplate struct {
+	Marker string
+	Result *plogons.PullRequestValidationResult
+	Err    error
+}
+
+func buildComment(result *plogons.PullRequestValidationResult, validationErr error) (string, error) {
+	return html.RenderText(&commentTemplate{
+		Marker: commentMarker,
+		Result: result,
+		Err:    validationErr,
+	}, "pr-comment")
+}
+
+// findComment finds the existing validation comment on a pull request, if
+// there is one. Only comments made by the provided user are considered, so
+// other users can't hijack the comment by copying the marker.
+func findComment(ctx context.Context, client *github.Client, pr *github.PullRequest, login string) (*github.IssueComment, error) {
+	owner, repo := repoName(pr)
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, res, err := client.Issues.ListComments(ctx, owner, repo, pr.GetNumber(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range comments {
+			if c.GetUser().GetLogin() == login && strings.HasPrefix(c.GetBody(), commentMarker) {
+				return c, nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return nil, nil
+		}
+
+		opts.Page = res.NextPage
+	}
+}
+
+// upsertComment updates the comment with the provided ID, or creates a new
+// comment if the ID is 0 or the comment no longer exists. The ID of the
+// comment is returned.
+func upsertComment(ctx context.Context, client *github.Client, pr *github.PullRequest, commentId int64, body string) (int64, error) {
+	owner, repo := repoName(pr)
+	if commentId != 0 {
+		c, res, err := client.Issues.EditComment(ctx, owner, repo, commentId, &github.IssueComment{
+			Body: &body,
+		})
+		if err == nil {
+			return c.GetID(), nil
+		}
+
+		// Recreate the comment if someone deleted it
+		if res == nil || res.StatusCode != 404 {
+			return 0, err
+		}
+	}
+
+	c, _, err := client.Issues.CreateComment(ctx, owner, repo, pr.GetNumber(), &github.IssueComment{
+		Body: &body,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return c.GetID(), nil
+}
+
+// repoName returns the owner and name of the repository a pull request
+// was opened against.
+func repoName(pr *github.PullRequest) (string, string) {
+	repo := pr.GetBase().GetRepo()
+	return repo.GetOwner().GetLogin(), repo.GetName()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+func describe(pr *github.PullRequest) string {
+	return fmt.Sprintf("#%d (%s)", pr.GetNumber(), shortSHA(pr.GetHead().GetSHA()))
+}
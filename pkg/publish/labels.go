@@ -0,0 +1,103 @@
+package publish
+
+import (
+	"context"
+	"os"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+)
+
+// Labels holds the labels the Operator owns. It never touches any other
+// labels on a pull request.
+type Labels struct {
+	// NeedsFixes is applied to pull requests with error findings
+	NeedsFixes string
+
+	// Passed is applied to pull requests without error findings
+	Passed string
+}
+
+// LabelsFromEnv returns the owned labels, which can be overridden with
+// OPERATOR_LABEL_NEEDS_FIXES and OPERATOR_LABEL_PASSED.
+func LabelsFromEnv() *Labels {
+	labels := &Labels{
+		NeedsFixes: os.Getenv("OPERATOR_LABEL_NEEDS_FIXES"),
+		Passed:     os.Getenv("OPERATOR_LABEL_PASSED"),
+	}
+
+	if labels.NeedsFixes == "" {
+		labels.NeedsFixes = "needs-fixes"
+	}
+
+	if labels.Passed == "" {
+		labels.Passed = "validation-passed"
+	}
+
+	return labels
+}
+
+// labelChange is a single label added to or removed from a pull request.
+type labelChange struct {
+	Label string
+	Added bool
+}
+
+// diff returns the changes needed to bring the pull request's owned labels
+// in line with the validation outcome.
+func (l *Labels) diff(pr *github.PullRequest, passed bool) []*labelChange {
+	current := make(map[string]bool)
+	for _, label := range pr.Labels {
+		current[label.GetName()] = true
+	}
+
+	want, unwanted := l.NeedsFixes, l.Passed
+	if passed {
+		want, unwanted = l.Passed, l.NeedsFixes
+	}
+
+	changes := make([]*labelChange, 0)
+	if !current[want] {
+		changes = append(changes, &labelChange{Label: want, Added: true})
+	}
+
+	if current[unwanted] {
+		changes = append(changes, &labelChange{Label: unwanted, Added: false})
+	}
+
+	return changes
+}
+
+func applyLabelChange(ctx context.Context, client *github.Client, pr *github.PullRequest, change *labelChange) error {
+	owner, repo := repoName(pr)
+	if change.Added {
+		_, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repo, pr.GetNumber(), []string{change.Label})
+		return err
+	}
+
+	res, err := client.Issues.RemoveLabelForIssue(ctx, owner, repo, pr.GetNumber(), change.Label)
+	if res != nil && res.StatusCode == 404 {
+		// Someone else already removed it
+		return nil
+	}
+
+	return err
+}
+
+func storeLabelChange(conn *pgx.Conn, pr *github.PullRequest, change *labelChange, reason, findingsHash string) error {
+	_, err := conn.Exec(`
+		INSERT INTO LabelChange (pr_number, head_sha, label, added, reason, findings_hash, changed_time)
+		VALUES
+			($1, $2, $3, $4, $5, $6, now());
+	`, pr.GetNumber(), pr.GetHead().GetSHA(), change.Label, change.Added, reason, findingsHash)
+	return err
+}
+
+// String describes the change, for logging.
+func (c *labelChange) String() string {
+	if c.Added {
+		return "add label " + c.Label
+	}
+
+	return "remove label " + c.Label
+}
@@ -0,0 +1,259 @@
+package publish
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"log"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/pullrequests"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// PublishJob posts the validation results stored by the sync job back to
+// open pull requests. Results can be posted as a single sticky comment,
+// which is only updated when the findings change, as a commit status or
+// check run on each new head commit, and as labels.
+type PublishJob struct {
+	Pool   *pgx.ConnPool
+	Client *github.Client
+
+	// Comments enables the sticky validation comment
+	Comments bool
+
+	// Checks is the kind of check to create on head commits, if any
+	Checks string
+
+	// Labels are the labels to manage based on the validation outcome, or
+	// nil if labels shouldn't be managed
+	Labels *Labels
+
+	// DryRun logs changes instead of making them
+	DryRun bool
+
+	// login is the user the client is authenticated as
+	login string
+
+	// dryRunStates stands in for the database in dry-run mode, so the same
+	// comment isn't logged on every run
+	dryRunStates map[int]*commentState
+}
+
+func (j *PublishJob) Execute() {
+	log.Println("Publishing validation results to pull requests")
+
+	ctx := context.Background()
+	if j.login == "" && !j.DryRun {
+		user, _, err := j.Client.Users.Get(ctx, "")
+		if err != nil {
+			log.Printf("Unable to retrieve authenticated GitHub user: %v\n", err)
+			return
+		}
+
+		j.login = user.GetLogin()
+	}
+
+	conn, err := j.Pool.Acquire()
+	if err != nil {
+		log.Printf("Failed to acquire database connection: %v\n", err)
+		return
+	}
+	defer j.Pool.Release(conn)
+
+	stored, err := pullrequests.ListOpen(conn)
+	if err != nil {
+		log.Printf("Failed to retrieve pull requests: %v\n", err)
+		return
+	}
+
+	validations, err := pullrequests.GetOpenValidations(conn)
+	if err != nil {
+		log.Printf("Failed to retrieve validations: %v\n", err)
+		return
+	}
+
+	for _, pr := range stored {
+		// Pull requests are validated by the sync job, so anything that
+		// hasn't been validated at its head commit yet is published later
+		v := validations[pr.GetNumber()]
+		if v == nil || pr.ValidationStale || v.HeadSHA != pr.GetHead().GetSHA() {
+			continue
+		}
+
+		err := j.publish(ctx, conn, pr.PullRequest, v.Result, v.Err)
+		if err != nil {
+			log.Printf("Unable to publish validation results to %s: %v\n", describe(pr.PullRequest), err)
+		}
+	}
+}
+
+func (j *PublishJob) publish(ctx context.Context, conn *pgx.Conn, pr *github.PullRequest, result *plogons.PullRequestValidationResult, validationErr error) error {
+	headSHA := pr.GetHead().GetSHA()
+	state, err := j.getState(conn, pr.GetNumber())
+	if err != nil {
+		return err
+	}
+
+	// Nothing can have changed if the head commit and the findings are the
+	// same. Failed validations are retried by the sync job, which changes
+	// the findings once it succeeds.
+	hash := findingsHash(result, validationErr)
+	if state != nil && state.HeadSHA == headSHA && state.FindingsHash == hash {
+		return nil
+	}
+
+	body, err := buildComment(result, validationErr)
+	if err != nil {
+		return err
+	}
+
+	// Checks are attached to commits, so every new head commit needs one
+	err = j.publishCheck(ctx, pr, result, validationErr, body)
+	if err != nil {
+		return err
+	}
+
+	err = j.publishLabels(ctx, conn, pr, result, validationErr, hash)
+	if err != nil {
+		return err
+	}
+
+	newState := &commentState{
+		HeadSHA:      headSHA,
+		FindingsHash: hash,
+	}
+
+	if state != nil {
+		newState.CommentID = state.CommentID
+	}
+
+	commented := state != nil && (state.CommentID != 0 || j.DryRun)
+	if !j.Comments || (commented && state.FindingsHash == hash) {
+		return j.storeState(conn, pr.GetNumber(), newState)
+	}
+
+	if j.DryRun {
+		log.Printf("Dry run: would post validation comment to %s:\n%s\n", describe(pr), body)
+		return j.storeState(conn, pr.GetNumber(), newState)
+	}
+
+	if newState.CommentID == 0 {
+		// The comment may have been posted before the state was stored
+		existing, err := findComment(ctx, j.Client, pr, j.login)
+		if err != nil {
+			return err
+		}
+
+		newState.CommentID = existing.GetID()
+	}
+
+	log.Printf("Posting validation comment to %s\n", describe(pr))
+	newState.CommentID, err = upsertComment(ctx, j.Client, pr, newState.CommentID, body)
+	if err != nil {
+		return err
+	}
+
+	return j.storeState(conn, pr.GetNumber(), newState)
+}
+
+func (j *PublishJob) publishCheck(ctx context.Context, pr *github.PullRequest, result *plogons.PullRequestValidationResult, validationErr error, summary string) error {
+	if j.Checks == ChecksNone {
+		return nil
+	}
+
+	if j.DryRun {
+		log.Printf("Dry run: would create %s on %s: %s\n", j.Checks, describe(pr), summarize(result, validationErr))
+		return nil
+	}
+
+	log.Printf("Creating %s on %s\n", j.Checks, describe(pr))
+	switch j.Checks {
+	case ChecksStatus:
+		return createStatus(ctx, j.Client, pr, result, validationErr)
+	case ChecksCheckRun:
+		return createCheckRun(ctx, j.Client, pr, result, validationErr, summary)
+	default:
+		return fmt.Errorf("unknown check mode %q", j.Checks)
+	}
+}
+
+// publishLabels adds and removes the owned labels on a pull request, and
+// records why each label was changed. Labels are left alone if the pull
+// request couldn't be validated, since that isn't the author's fault.
+func (j *PublishJob) publishLabels(ctx context.Context, conn *pgx.Conn, pr *github.PullRequest, result *plogons.PullRequestValidationResult, validationErr error, hash string) error {
+	if j.Labels == nil || validationErr != nil {
+		return nil
+	}
+
+	reason := summarize(result, validationErr)
+	for _, change := range j.Labels.diff(pr, passed(result, validationErr)) {
+		if j.DryRun {
+			log.Printf("Dry run: would %s on %s: %s\n", change, describe(pr), reason)
+			continue
+		}
+
+		log.Printf("Applying %s on %s: %s\n", change, describe(pr), reason)
+		err := applyLabelChange(ctx, j.Client, pr, change)
+		if err != nil {
+			return err
+		}
+
+		err = storeLabelChange(conn, pr, change, reason, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *PublishJob) getState(conn *pgx.Conn, prNumber int) (*commentState, error) {
+	if j.DryRun {
+		return j.dryRunStates[prNumber], nil
+	}
+
+	return getCommentState(conn, prNumber)
+}
+
+func (j *PublishJob) storeState(conn *pgx.Conn, prNumber int, s *commentState) error {
+	if j.DryRun {
+		if j.dryRunStates == nil {
+			j.dryRunStates = make(map[int]*commentState)
+		}
+
+		j.dryRunStates[prNumber] = s
+		return nil
+	}
+
+	return storeCommentState(conn, prNumber, s)
+}
+
+// findingsHash hashes the outcome of validating a pull request, whether
+// it's a set of findings or an error.
+func findingsHash(result *plogons.PullRequestValidationResult, validationErr error) string {
+	if validationErr != nil {
+		h := sha256.Sum256([]byte("error\x00" + validationErr.Error()))
+		return hex.EncodeToString(h[:])
+	}
+
+	return result.Hash()
+}
+
+func (j *PublishJob) Description() string {
+	return "PublishJob"
+}
+
+func (j *PublishJob) Key() int {
+	h := fnv.New32a()
+	_, err := h.Write([]byte(j.Description()))
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+
+	return int(h.Sum32())
+}
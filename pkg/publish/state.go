@@ -0,0 +1,45 @@
+package publish
+
+import (
+	"github.com/jackc/pgx"
+)
+
+// commentState is what was last published to a pull request. The head
+// commit and findings are recorded even if comments are disabled, so that
+// each validation is only published once.
+type commentState struct {
+	CommentID    int64
+	HeadSHA      string
+	FindingsHash string
+}
+
+func getCommentState(conn *pgx.Conn, prNumber int) (*commentState, error) {
+	s := &commentState{}
+	err := conn.QueryRow(`
+		SELECT comment_id, head_sha, findings_hash
+		FROM PullRequestComment
+		WHERE pr_number = $1;
+	`, prNumber).Scan(&s.CommentID, &s.HeadSHA, &s.FindingsHash)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func storeCommentState(conn *pgx.Conn, prNumber int, s *commentState) error {
+	_, err := conn.Exec(`
+		INSERT INTO PullRequestComment (pr_number, comment_id, head_sha, findings_hash, updated_time)
+		VALUES
+			($1, $2, $3, $4, now())
+		ON CONFLICT (pr_number) DO UPDATE
+		SET
+			comment_id = EXCLUDED.comment_id,
+			head_sha = EXCLUDED.head_sha,
+			findings_hash = EXCLUDED.findings_hash,
+			updated_time = EXCLUDED.updated_time;
+	`, prNumber, s.CommentID, s.HeadSHA, s.FindingsHash)
+	return err
+}
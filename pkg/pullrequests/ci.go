@@ -0,0 +1,115 @@
+package pullrequests
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// StoreCIStatus saves the combined outcome of the checks on a commit.
+func StoreCIStatus(conn *pgx.Conn, sha string, status *plogons.CIStatus) error {
+	checks, err := json.Marshal(status.Checks)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(`
+		INSERT INTO CIStatus (head_sha, state, checks, updated_time)
+		VALUES
+			($1, $2, $3::JSONB, now())
+		ON CONFLICT (head_sha) DO UPDATE
+		SET
+			state = EXCLUDED.state,
+			checks = EXCLUDED.checks,
+			updated_time = EXCLUDED.updated_time;
+	`, sha, string(status.State), string(checks))
+	return err
+}
+
+// UpdateCIStatus retrieves and stores the checks on a commit, if it's the
+// head commit of an open pull request.
+func UpdateCIStatus(conn *pgx.Conn, sha string) error {
+	var open bool
+	err := conn.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM PullRequest WHERE state = 'open' AND head_sha = $1);
+	`, sha).Scan(&open)
+	if err != nil || !open {
+		return err
+	}
+
+	status, err := plogons.GetCIStatus(sha)
+	if err != nil {
+		return err
+	}
+
+	return StoreCIStatus(conn, sha, status)
+}
+
+// syncCI retrieves the checks on the head commit of every open pull request
+// whose checks haven't been retrieved yet or haven't all completed. Commits
+// without any checks are retrieved again too, since their checks may not
+// have started when they were last retrieved.
+func syncCI(conn *pgx.Conn) error {
+	stored, err := ListOpen(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, pr := range stored {
+		if pr.CI != nil && pr.CI.State != plogons.CIPending && pr.CI.State != plogons.CINone {
+			continue
+		}
+
+		log.Printf("Retrieving checks of pull request #%d\n", pr.GetNumber())
+		status, err := plogons.GetCIStatus(pr.GetHead().GetSHA())
+		if err != nil {
+			return err
+		}
+
+		err = StoreCIStatus(conn, pr.GetHead().GetSHA(), status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getOpenCIStatuses(conn *pgx.Conn) (map[string]*plogons.CIStatus, error) {
+	rows, err := conn.Query(`
+		SELECT CIStatus.head_sha, CIStatus.state, CIStatus.checks::TEXT
+		FROM CIStatus
+		JOIN PullRequest
+			ON PullRequest.head_sha = CIStatus.head_sha
+		WHERE PullRequest.state = 'open';
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]*plogons.CIStatus)
+	for rows.Next() {
+		var sha, state, checks string
+		err := rows.Scan(&sha, &state, &checks)
+		if err != nil {
+			return nil, err
+		}
+
+		status := &plogons.CIStatus{State: plogons.CIState(state)}
+		err = json.Unmarshal([]byte(checks), &status.Checks)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses[sha] = status
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,91 @@
+package pullrequests
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+)
+
+// UpdateLabel applies a change to a repository label to every stored pull
+// request that has it. oldName is the name of the label before the change,
+// and label is nil if the label was deleted.
+func UpdateLabel(conn *pgx.Conn, oldName string, label *github.Label) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT number, data::TEXT
+		FROM PullRequest
+		WHERE data->'labels' @> jsonb_build_array(jsonb_build_object('name', $1::TEXT))
+		FOR UPDATE;
+	`, oldName)
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[int]string)
+	for rows.Next() {
+		var number int
+		var data string
+		err := rows.Scan(&number, &data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		pr := &github.PullRequest{}
+		err = json.Unmarshal([]byte(data), pr)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		labels := make([]*github.Label, 0, len(pr.Labels))
+		for _, l := range pr.Labels {
+			if l.GetName() != oldName {
+				labels = append(labels, l)
+			} else if label != nil {
+				labels = append(labels, label)
+			}
+		}
+
+		pr.Labels = labels
+		newData, err := json.Marshal(pr)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		updated[number] = string(newData)
+	}
+
+	rows.Close()
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	for number, data := range updated {
+		_, err := tx.Exec("UPDATE PullRequest SET data = $2::JSONB WHERE number = $1;", number, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if label == nil {
+		_, err = tx.Exec("DELETE FROM PullRequestLabel WHERE name = $1;", oldName)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE PullRequestLabel SET name = $2, color = $3
+			WHERE name = $1;
+		`, oldName, label.GetName(), label.GetColor())
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
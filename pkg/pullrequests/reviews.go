@@ -0,0 +1,185 @@
+package pullrequests
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// StoreReview saves a review submitted on a pull request. Reviews that are
+// still pending haven't been submitted, and are ignored. Webhooks send review
+// states in lowercase, so they're stored in the uppercase form the API uses.
+func StoreReview(conn *pgx.Conn, number int, review *github.PullRequestReview) error {
+	if review.SubmittedAt == nil {
+		return nil
+	}
+
+	_, err := conn.Exec(`
+		INSERT INTO PullRequestReview (id, pr_number, reviewer, state, submitted_time)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE
+		SET
+			state = EXCLUDED.state;
+	`, review.GetID(), number, review.GetUser().GetLogin(), strings.ToUpper(review.GetState()), review.GetSubmittedAt())
+	return err
+}
+
+// syncReviews retrieves and stores all of the reviews on a pull request.
+func syncReviews(conn *pgx.Conn, number int) error {
+	reviews, err := plogons.GetReviews(number)
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		err := StoreReview(conn, number, review)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.Exec("UPDATE PullRequest SET reviews_synced = TRUE WHERE number = $1;", number)
+	return err
+}
+
+// maxReviewBackfill is the number of pull requests whose reviews are
+// backfilled per sync, so that the backfill doesn't exhaust the API rate
+// limit.
+const maxReviewBackfill = 20
+
+// backfillReviews retrieves the reviews of pull requests whose reviews have
+// never been retrieved, such as ones stored before reviews were tracked.
+// Closed pull requests are included, since their reviews are needed for the
+// statistics. Open pull requests go first.
+func backfillReviews(conn *pgx.Conn) error {
+	rows, err := conn.Query(`
+		SELECT number
+		FROM PullRequest
+		WHERE NOT reviews_synced
+		ORDER BY state = 'open' DESC, number DESC
+		LIMIT $1;
+	`, maxReviewBackfill)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	numbers := make([]int, 0)
+	for rows.Next() {
+		var number int
+		err := rows.Scan(&number)
+		if err != nil {
+			return err
+		}
+
+		numbers = append(numbers, number)
+	}
+
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+
+	rows.Close()
+
+	for _, number := range numbers {
+		log.Printf("Backfilling reviews of pull request #%d\n", number)
+		err := syncReviews(conn, number)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Review is a review submitted on a pull request.
+type Review struct {
+	Reviewer  string
+	State     string
+	Submitted time.Time
+}
+
+func getOpenReviews(conn *pgx.Conn) (map[int][]*Review, error) {
+	rows, err := conn.Query(`
+		SELECT PullRequestReview.pr_number, PullRequestReview.reviewer, PullRequestReview.state,
+			PullRequestReview.submitted_time
+		FROM PullRequestReview
+		JOIN PullRequest
+			ON PullRequest.number = PullRequestReview.pr_number
+		WHERE PullRequest.state = 'open'
+		ORDER BY PullRequestReview.submitted_time;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make(map[int][]*Review)
+	for rows.Next() {
+		var number int
+		r := &Review{}
+		err := rows.Scan(&number, &r.Reviewer, &r.State, &r.Submitted)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews[number] = append(reviews[number], r)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return reviews, nil
+}
+
+// Plogon converts a stored pull request into its report representation,
+// including its review status and the checks on its head commit. Like on
+// GitHub, each reviewer's latest approval or change request counts towards
+// the review state, and reviews by the submitter, such as replies to review
+// comments, count as activity by them instead.
+func (pr *StoredPullRequest) Plogon() *plogons.Plogon {
+	p := plogons.NewPlogon(pr.PullRequest)
+
+	submitter := pr.GetUser().GetLogin()
+	authorActivity := pr.AuthorActivity
+	decisions := make(map[string]string)
+	for _, r := range pr.Reviews {
+		if r.Reviewer == submitter {
+			if r.Submitted.After(authorActivity) {
+				authorActivity = r.Submitted
+			}
+
+			continue
+		}
+
+		p.LastReviewed = r.Submitted
+
+		switch r.State {
+		case "APPROVED", "CHANGES_REQUESTED":
+			decisions[r.Reviewer] = r.State
+		case "DISMISSED":
+			delete(decisions, r.Reviewer)
+		}
+	}
+
+	for _, decision := range decisions {
+		if decision == "CHANGES_REQUESTED" {
+			p.ReviewState = plogons.ReviewChangesRequested
+			break
+		}
+
+		p.ReviewState = plogons.ReviewApproved
+	}
+
+	p.AuthorResponded = !p.LastReviewed.IsZero() && authorActivity.After(p.LastReviewed)
+	p.LastAuthorActivity = authorActivity
+	p.CI = pr.CI
+
+	return p
+}
@@ -0,0 +1,263 @@
+package pullrequests
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// Store saves the state of a pull request, as received from GitHub, along
+// with its labels. States older than the one already stored are ignored,
+// since webhooks can be delivered out of order. If invalidate is set, the
+// pull request is marked as needing to be validated again. A new head commit
+// counts as activity by the submitter.
+func Store(conn *pgx.Conn, pr *github.PullRequest, invalidate bool) error {
+	data, err := json.Marshal(pr)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tag, err := tx.Exec(`
+		INSERT INTO PullRequest (number, state, head_sha, updated_time, data, validation_stale,
+			title, submitter, url, created_time, first_seen_time, closed_time, merged, author_activity_time)
+		VALUES
+			($1, $2, $3, $4, $5::JSONB, TRUE, $7, $8, $9, $10, now(), $11, $12, $10)
+		ON CONFLICT (number) DO UPDATE
+		SET
+			state = EXCLUDED.state,
+			head_sha = EXCLUDED.head_sha,
+			author_activity_time = CASE
+				WHEN PullRequest.head_sha <> EXCLUDED.head_sha
+					THEN GREATEST(PullRequest.author_activity_time, EXCLUDED.updated_time)
+				ELSE PullRequest.author_activity_time
+			END,
+			updated_time = EXCLUDED.updated_time,
+			data = EXCLUDED.data,
+			validation_stale = PullRequest.validation_stale OR $6,
+			title = EXCLUDED.title,
+			submitter = EXCLUDED.submitter,
+			url = EXCLUDED.url,
+			created_time = EXCLUDED.created_time,
+			closed_time = EXCLUDED.closed_time,
+			merged = EXCLUDED.merged
+		WHERE PullRequest.updated_time <= EXCLUDED.updated_time;
+	`, pr.GetNumber(), pr.GetState(), pr.GetHead().GetSHA(), pr.GetUpdatedAt(), string(data), invalidate,
+		pr.GetTitle(), pr.GetUser().GetLogin(), pr.GetHTMLURL(), pr.GetCreatedAt(), pr.ClosedAt, pr.MergedAt != nil)
+	if err != nil {
+		return err
+	}
+
+	// The stored state was newer
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec("DELETE FROM PullRequestLabel WHERE pr_number = $1;", pr.GetNumber())
+	if err != nil {
+		return err
+	}
+
+	for _, label := range pr.Labels {
+		_, err := tx.Exec(`
+			INSERT INTO PullRequestLabel (pr_number, name, color)
+			VALUES
+				($1, $2, $3)
+			ON CONFLICT DO NOTHING;
+		`, pr.GetNumber(), label.GetName(), label.GetColor())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetResolvedBy records who merged or closed a pull request.
+func SetResolvedBy(conn *pgx.Conn, number int, login string) error {
+	_, err := conn.Exec("UPDATE PullRequest SET resolved_by = $2 WHERE number = $1;", number, login)
+	return err
+}
+
+// RecordAuthorActivity records that the submitter of a pull request was
+// active on it, such as by commenting.
+func RecordAuthorActivity(conn *pgx.Conn, number int, t time.Time) error {
+	_, err := conn.Exec(`
+		UPDATE PullRequest SET author_activity_time = GREATEST(author_activity_time, $2)
+		WHERE number = $1;
+	`, number, t)
+	return err
+}
+
+// Invalidate marks a pull request as needing to be validated again.
+func Invalidate(conn *pgx.Conn, number int) error {
+	_, err := conn.Exec("UPDATE PullRequest SET validation_stale = TRUE WHERE number = $1;", number)
+	return err
+}
+
+// StoredPullRequest is a pull request loaded from the database.
+type StoredPullRequest struct {
+	*github.PullRequest
+	ValidationStale bool
+	AuthorActivity  time.Time
+	Reviews         []*Review
+	CI              *plogons.CIStatus
+}
+
+// ListOpen returns all open pull requests, ordered by when they were last
+// updated. Their labels, reviews and the checks on their head commits are
+// loaded from the PullRequestLabel, PullRequestReview and CIStatus tables.
+func ListOpen(conn *pgx.Conn) ([]*StoredPullRequest, error) {
+	rows, err := conn.Query(`
+		SELECT data::TEXT, validation_stale, author_activity_time
+		FROM PullRequest
+		WHERE state = 'open'
+		ORDER BY updated_time DESC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prs := make([]*StoredPullRequest, 0)
+	for rows.Next() {
+		var data string
+		var authorActivity *time.Time
+		pr := &StoredPullRequest{}
+		err := rows.Scan(&data, &pr.ValidationStale, &authorActivity)
+		if err != nil {
+			return nil, err
+		}
+
+		if authorActivity != nil {
+			pr.AuthorActivity = *authorActivity
+		}
+
+		err = json.Unmarshal([]byte(data), &pr.PullRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		prs = append(prs, pr)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	rows.Close()
+
+	labels, err := getOpenLabels(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := getOpenReviews(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	ciStatuses, err := getOpenCIStatuses(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pr := range prs {
+		pr.Labels = labels[pr.GetNumber()]
+		pr.Reviews = reviews[pr.GetNumber()]
+		pr.CI = ciStatuses[pr.GetHead().GetSHA()]
+	}
+
+	return prs, nil
+}
+
+func getOpenLabels(conn *pgx.Conn) (map[int][]*github.Label, error) {
+	rows, err := conn.Query(`
+		SELECT PullRequestLabel.pr_number, PullRequestLabel.name, PullRequestLabel.color
+		FROM PullRequestLabel
+		JOIN PullRequest
+			ON PullRequest.number = PullRequestLabel.pr_number
+		WHERE PullRequest.state = 'open'
+		ORDER BY PullRequestLabel.name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make(map[int][]*github.Label)
+	for rows.Next() {
+		var number int
+		label := &github.Label{
+			Name:  new(string),
+			Color: new(string),
+		}
+
+		err := rows.Scan(&number, label.Name, label.Color)
+		if err != nil {
+			return nil, err
+		}
+
+		labels[number] = append(labels[number], label)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return labels, nil
+}
+
+// ListResolved returns the pull requests merged or closed after the provided
+// time.
+func ListResolved(conn *pgx.Conn, since time.Time) ([]*plogons.ResolvedPlogon, error) {
+	rows, err := conn.Query(`
+		SELECT number, title, url, head_sha, submitter, updated_time, merged, resolved_by, closed_time
+		FROM PullRequest
+		WHERE state = 'closed' AND closed_time > $1
+		ORDER BY number;
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resolved := make([]*plogons.ResolvedPlogon, 0)
+	for rows.Next() {
+		var merged bool
+		var resolvedBy *string
+		r := &plogons.ResolvedPlogon{
+			Plogon: &plogons.Plogon{},
+			State:  "closed",
+		}
+
+		err := rows.Scan(&r.Number, &r.Title, &r.URL, &r.HeadSHA, &r.Submitter, &r.Updated, &merged, &resolvedBy, &r.Resolved)
+		if err != nil {
+			return nil, err
+		}
+
+		if merged {
+			r.State = "merged"
+		}
+
+		if resolvedBy != nil {
+			r.ResolvedBy = *resolvedBy
+		}
+
+		resolved = append(resolved, r)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return resolved, nil
+}
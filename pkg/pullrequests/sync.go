@@ -0,0 +1,185 @@
+package pullrequests
+
+import (
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// SyncJob keeps the stored pull requests and the checks on their head commits
+// up to date with GitHub, and validates any pull requests whose validation is
+// missing or stale.
+type SyncJob struct {
+	Pool *pgx.ConnPool
+}
+
+func (j *SyncJob) Execute() {
+	log.Println("Synchronizing plugin pull requests")
+
+	conn, err := j.Pool.Acquire()
+	if err != nil {
+		log.Printf("Failed to acquire database connection: %v\n", err)
+		return
+	}
+	defer j.Pool.Release(conn)
+
+	err = syncOpen(conn)
+	if err != nil {
+		log.Printf("Failed to synchronize pull requests: %v\n", err)
+		return
+	}
+
+	err = backfillReviews(conn)
+	if err != nil {
+		log.Printf("Failed to backfill pull request reviews: %v\n", err)
+	}
+
+	err = syncCI(conn)
+	if err != nil {
+		log.Printf("Failed to retrieve pull request checks: %v\n", err)
+	}
+
+	err = validateOpen(conn)
+	if err != nil {
+		log.Printf("Failed to validate pull requests: %v\n", err)
+		return
+	}
+}
+
+// syncOpen stores all open pull requests, and updates the ones that are no
+// longer open with their final state. Reviews are only retrieved for pull
+// requests that were updated since they were last stored, since submitting a
+// review updates its pull request. Pull requests whose reviews were never
+// retrieved are caught up by backfillReviews.
+func syncOpen(conn *pgx.Conn) error {
+	_, plogonPRs, err := plogons.GetPlogons()
+	if err != nil {
+		return err
+	}
+
+	previous, err := ListOpen(conn)
+	if err != nil {
+		return err
+	}
+
+	previousUpdated := make(map[int]time.Time, len(previous))
+	for _, pr := range previous {
+		previousUpdated[pr.GetNumber()] = pr.GetUpdatedAt()
+	}
+
+	open := make(map[int]bool, len(plogonPRs))
+	for _, pr := range plogonPRs {
+		open[pr.GetNumber()] = true
+
+		err := Store(conn, pr, false)
+		if err != nil {
+			return err
+		}
+
+		updated, ok := previousUpdated[pr.GetNumber()]
+		if ok && !pr.GetUpdatedAt().After(updated) {
+			continue
+		}
+
+		err = syncReviews(conn, pr.GetNumber())
+		if err != nil {
+			return err
+		}
+	}
+
+	stored, err := ListOpen(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range stored {
+		if open[s.GetNumber()] {
+			continue
+		}
+
+		log.Printf("Pull request #%d is no longer open\n", s.GetNumber())
+		pr, err := plogons.GetPullRequest(s.GetNumber())
+		if err != nil {
+			return err
+		}
+
+		err = Store(conn, pr, false)
+		if err != nil {
+			return err
+		}
+
+		err = syncReviews(conn, pr.GetNumber())
+		if err != nil {
+			return err
+		}
+
+		if pr.GetState() != "closed" {
+			continue
+		}
+
+		resolvedBy, err := plogons.GetResolvedBy(pr)
+		if err != nil {
+			return err
+		}
+
+		err = SetResolvedBy(conn, pr.GetNumber(), resolvedBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOpen validates every open pull request that has been invalidated
+// or hasn't been validated at its head commit yet. Incomplete validations
+// are retried.
+func validateOpen(conn *pgx.Conn) error {
+	stored, err := ListOpen(conn)
+	if err != nil {
+		return err
+	}
+
+	validations, err := GetOpenValidations(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, pr := range stored {
+		v := validations[pr.GetNumber()]
+		if pr.Validated(v) {
+			continue
+		}
+
+		log.Printf("Validating pull request #%d\n", pr.GetNumber())
+		res, validationErr := plogons.ValidatePullRequest(pr.PullRequest)
+		err := StoreValidation(conn, pr.GetNumber(), &Validation{
+			HeadSHA: pr.GetHead().GetSHA(),
+			Result:  res,
+			Err:     validationErr,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *SyncJob) Description() string {
+	return "SyncJob"
+}
+
+func (j *SyncJob) Key() int {
+	h := fnv.New32a()
+	_, err := h.Write([]byte(j.Description()))
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+
+	return int(h.Sum32())
+}
@@ -0,0 +1,243 @@
+package pullrequests
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// Validation is the outcome of validating a pull request at a specific head
+// commit. Err is set if the pull request couldn't be validated at all.
+type Validation struct {
+	HeadSHA   string
+	Validated time.Time
+	Result    *plogons.PullRequestValidationResult
+	Err       error
+}
+
+// Incomplete reports whether the pull request or any of its plugins couldn't
+// be validated, such as when GitHub couldn't be reached.
+func (v *Validation) Incomplete() bool {
+	if v.Err != nil {
+		return true
+	}
+
+	for _, p := range v.Result.Plugins {
+		if p.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validated reports whether the provided validation is up to date with the
+// pull request, meaning it's for the head commit, the pull request hasn't
+// been invalidated since, and it's complete.
+func (pr *StoredPullRequest) Validated(v *Validation) bool {
+	return v != nil && !pr.ValidationStale && v.HeadSHA == pr.GetHead().GetSHA() && !v.Incomplete()
+}
+
+// StoreValidation records the outcome of validating a pull request, and
+// clears its stale flag if its head commit hasn't changed since. Incomplete
+// validations leave the flag as it is, so that they're retried.
+func StoreValidation(conn *pgx.Conn, number int, v *Validation) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var validationErr *string
+	if v.Err != nil {
+		msg := v.Err.Error()
+		validationErr = &msg
+	}
+
+	plugins := make([]string, 0)
+	pluginErrors := make([]string, 0)
+	passed := v.Err == nil
+	if v.Result != nil {
+		for _, p := range v.Result.Plugins {
+			plugins = append(plugins, p.Dir())
+			if p.Err != nil {
+				pluginErrors = append(pluginErrors, p.Err.Error())
+			} else {
+				pluginErrors = append(pluginErrors, "")
+			}
+		}
+
+		passed = passed && !v.Result.HasErrors()
+	}
+
+	var runId int
+	err = tx.QueryRow(`
+		INSERT INTO ValidationRun (pr_number, head_sha, validated_time, error, plugins, plugin_errors, passed)
+		VALUES
+			($1, $2, now(), $3, $4, $5, $6)
+		RETURNING id;
+	`, number, v.HeadSHA, validationErr, plugins, pluginErrors, passed).Scan(&runId)
+	if err != nil {
+		return err
+	}
+
+	if v.Result != nil {
+		position := 0
+		for _, p := range v.Result.Plugins {
+			for _, f := range p.Findings {
+				_, err := tx.Exec(`
+					INSERT INTO ValidationFinding (run_id, position, plugin, rule_id, severity, message, field, file)
+					VALUES
+						($1, $2, $3, $4, $5, $6, $7, $8);
+				`, runId, position, p.Dir(), f.ID, int16(f.Severity), f.Message, f.Field, f.File)
+				if err != nil {
+					return err
+				}
+
+				position++
+			}
+		}
+	}
+
+	if !v.Incomplete() {
+		_, err = tx.Exec(`
+			UPDATE PullRequest SET validation_stale = FALSE
+			WHERE number = $1 AND head_sha = $2;
+		`, number, v.HeadSHA)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetOpenValidations returns the most recent validation of each open pull
+// request. This may be for an older head commit, if the pull request hasn't
+// been validated since it was updated. Pull requests that have never been
+// validated are left out.
+func GetOpenValidations(conn *pgx.Conn) (map[int]*Validation, error) {
+	rows, err := conn.Query(`
+		SELECT DISTINCT ON (ValidationRun.pr_number)
+			ValidationRun.id, ValidationRun.pr_number, ValidationRun.head_sha, ValidationRun.validated_time,
+			ValidationRun.error, ValidationRun.plugins, ValidationRun.plugin_errors
+		FROM ValidationRun
+		JOIN PullRequest
+			ON PullRequest.number = ValidationRun.pr_number
+		WHERE PullRequest.state = 'open'
+		ORDER BY ValidationRun.pr_number, ValidationRun.validated_time DESC;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	validations := make(map[int]*Validation)
+	runs := make(map[int]*Validation)
+	for rows.Next() {
+		var runId, number int
+		var validationErr *string
+		var plugins, pluginErrors []string
+		v := &Validation{}
+		err := rows.Scan(&runId, &number, &v.HeadSHA, &v.Validated, &validationErr, &plugins, &pluginErrors)
+		if err != nil {
+			return nil, err
+		}
+
+		if validationErr != nil {
+			v.Err = errors.New(*validationErr)
+		} else {
+			v.Result = &plogons.PullRequestValidationResult{
+				Plugins: make([]*plogons.PlogonMetaValidationResult, len(plugins)),
+			}
+
+			for i, dir := range plugins {
+				p := newPluginResult(dir)
+				if i < len(pluginErrors) && pluginErrors[i] != "" {
+					p.Err = errors.New(pluginErrors[i])
+				}
+
+				v.Result.Plugins[i] = p
+			}
+		}
+
+		validations[number] = v
+		runs[runId] = v
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	rows.Close()
+
+	err = loadFindings(conn, runs)
+	if err != nil {
+		return nil, err
+	}
+
+	return validations, nil
+}
+
+// loadFindings adds the findings of the provided validation runs to their
+// plugins' results.
+func loadFindings(conn *pgx.Conn, runs map[int]*Validation) error {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	runIds := make([]int32, 0, len(runs))
+	for runId := range runs {
+		runIds = append(runIds, int32(runId))
+	}
+
+	rows, err := conn.Query(`
+		SELECT run_id, plugin, rule_id, severity, message, field, file
+		FROM ValidationFinding
+		WHERE run_id = ANY($1)
+		ORDER BY run_id, position;
+	`, runIds)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var runId int
+		var plugin string
+		var severity int16
+		f := &plogons.Finding{}
+		err := rows.Scan(&runId, &plugin, &f.ID, &severity, &f.Message, &f.Field, &f.File)
+		if err != nil {
+			return err
+		}
+
+		f.Severity = plogons.Severity(severity)
+
+		v := runs[runId]
+		if v == nil || v.Result == nil {
+			continue
+		}
+
+		for _, p := range v.Result.Plugins {
+			if p.Dir() == plugin {
+				p.Findings = append(p.Findings, f)
+				break
+			}
+		}
+	}
+
+	return rows.Err()
+}
+
+func newPluginResult(dir string) *plogons.PlogonMetaValidationResult {
+	p := &plogons.PlogonMetaValidationResult{
+		Findings: make([]*plogons.Finding, 0),
+	}
+
+	p.Channel, p.Name, _ = strings.Cut(dir, "/")
+	return p
+}
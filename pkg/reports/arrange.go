@@ -0,0 +1,249 @@
+package reports
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// Sort orders readers can choose for report rows.
+const (
+	SortUpdated  = "updated"
+	SortCreated  = "created"
+	SortProblems = "problems"
+	SortTitle    = "title"
+)
+
+// Groupings readers can choose for report rows.
+const (
+	GroupNone       = "none"
+	GroupLabel      = "label"
+	GroupValidation = "validation"
+	GroupChannel    = "channel"
+)
+
+// Arrange sorts the pull requests in each section of the digest and splits
+// them into groups. Pull requests awaiting a review from the reviewer come
+// first, if one is provided. Unknown or empty options fall back to sorting
+// by last update without grouping.
+func (d *ReportDigest) Arrange(sortBy, groupBy, reviewer string) {
+	less := sortLess(sortBy)
+
+	sort.SliceStable(d.New, func(i, j int) bool {
+		return less(d.New[i], d.New[j])
+	})
+
+	sort.SliceStable(d.Changed, func(i, j int) bool {
+		return less(d.Changed[i].ReportTemplate, d.Changed[j].ReportTemplate)
+	})
+
+	sort.SliceStable(d.Fixed, func(i, j int) bool {
+		return less(d.Fixed[i].ReportTemplate, d.Fixed[j].ReportTemplate)
+	})
+
+	d.Reviewer = ""
+	if reviewer != "" {
+		d.PrioritizeReviewer(reviewer)
+	}
+
+	d.group(groupBy)
+}
+
+// sortLess returns the comparison for a sort order. Pull requests updated or
+// opened most recently come first, as do the ones with the most problems.
+func sortLess(sortBy string) func(a, b *ReportTemplate) bool {
+	switch sortBy {
+	case SortCreated:
+		return func(a, b *ReportTemplate) bool {
+			return a.Plogon.Created.After(b.Plogon.Created)
+		}
+	case SortProblems:
+		return func(a, b *ReportTemplate) bool {
+			aErrors, aWarnings := problemCounts(a)
+			bErrors, bWarnings := problemCounts(b)
+			if aErrors != bErrors {
+				return aErrors > bErrors
+			}
+
+			return aWarnings > bWarnings
+		}
+	case SortTitle:
+		return func(a, b *ReportTemplate) bool {
+			return strings.ToLower(a.Plogon.Title) < strings.ToLower(b.Plogon.Title)
+		}
+	default:
+		return func(a, b *ReportTemplate) bool {
+			return a.Plogon.Updated.After(b.Plogon.Updated)
+		}
+	}
+}
+
+// problemCounts returns the number of errors and warnings found on a pull
+// request. Failing to validate it at all counts as an error.
+func problemCounts(rt *ReportTemplate) (int, int) {
+	if rt.ValidationState.Err != nil || rt.ValidationState.Result == nil {
+		return 1, 0
+	}
+
+	result := rt.ValidationState.Result
+	return result.Count(plogons.SeverityError), result.Count(plogons.SeverityWarning)
+}
+
+// group splits the sections of the digest into groups, keeping the order of
+// the pull requests within each one. Pull requests can be in several groups
+// when grouping by label or channel.
+func (d *ReportDigest) group(groupBy string) {
+	keys := groupKeys(groupBy)
+
+	groups := make([]*ReportGroup, 0)
+	byName := make(map[string]*ReportGroup)
+	get := func(name string) *ReportGroup {
+		g, ok := byName[name]
+		if !ok {
+			g = &ReportGroup{Name: name}
+			byName[name] = g
+			groups = append(groups, g)
+		}
+
+		return g
+	}
+
+	for _, rt := range d.New {
+		for _, name := range keys(rt) {
+			g := get(name)
+			g.New = append(g.New, rt)
+		}
+	}
+
+	for _, c := range d.Changed {
+		for _, name := range keys(c.ReportTemplate) {
+			g := get(name)
+			g.Changed = append(g.Changed, c)
+		}
+	}
+
+	for _, f := range d.Fixed {
+		for _, name := range keys(f.ReportTemplate) {
+			g := get(name)
+			g.Fixed = append(g.Fixed, f)
+		}
+	}
+
+	order := groupOrder(groupBy)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return order(groups[i].Name, groups[j].Name)
+	})
+
+	d.Groups = groups
+}
+
+// Group names that aren't derived from the pull requests themselves
+const (
+	groupUnlabeled        = "Unlabeled"
+	groupValidationFailed = "Validation failed"
+	groupErrors           = "Errors"
+	groupWarnings         = "Warnings"
+	groupNoProblems       = "No problems"
+	groupTesting          = "Testing"
+	groupStable           = "Stable"
+	groupOther            = "Other"
+)
+
+// groupKeys returns the function that names the groups a pull request
+// belongs to. Ungrouped pull requests all belong to a single unnamed group.
+func groupKeys(groupBy string) func(rt *ReportTemplate) []string {
+	switch groupBy {
+	case GroupLabel:
+		return func(rt *ReportTemplate) []string {
+			if len(rt.Plogon.Labels) == 0 {
+				return []string{groupUnlabeled}
+			}
+
+			names := make([]string, len(rt.Plogon.Labels))
+			for i, label := range rt.Plogon.Labels {
+				names[i] = label.Name
+			}
+
+			return names
+		}
+	case GroupValidation:
+		return func(rt *ReportTemplate) []string {
+			if rt.ValidationState.Err != nil || rt.ValidationState.Result == nil {
+				return []string{groupValidationFailed}
+			}
+
+			errors, warnings := problemCounts(rt)
+			switch {
+			case errors > 0:
+				return []string{groupErrors}
+			case warnings > 0:
+				return []string{groupWarnings}
+			default:
+				return []string{groupNoProblems}
+			}
+		}
+	case GroupChannel:
+		return func(rt *ReportTemplate) []string {
+			if rt.ValidationState.Result == nil {
+				return []string{groupOther}
+			}
+
+			channels := make([]string, 0, 2)
+			seen := make(map[string]bool)
+			for _, plugin := range rt.ValidationState.Result.Plugins {
+				name := groupOther
+				switch plugin.Channel {
+				case "testing":
+					name = groupTesting
+				case "plugins":
+					name = groupStable
+				}
+
+				if !seen[name] {
+					seen[name] = true
+					channels = append(channels, name)
+				}
+			}
+
+			if len(channels) == 0 {
+				return []string{groupOther}
+			}
+
+			return channels
+		}
+	default:
+		return func(rt *ReportTemplate) []string {
+			return []string{""}
+		}
+	}
+}
+
+// groupOrder returns the comparison for group names. Labels are sorted by
+// name, while the other groupings have a fixed order.
+func groupOrder(groupBy string) func(a, b string) bool {
+	var fixed []string
+	switch groupBy {
+	case GroupLabel:
+		return func(a, b string) bool {
+			if a == groupUnlabeled || b == groupUnlabeled {
+				return b == groupUnlabeled && a != groupUnlabeled
+			}
+
+			return strings.ToLower(a) < strings.ToLower(b)
+		}
+	case GroupValidation:
+		fixed = []string{groupValidationFailed, groupErrors, groupWarnings, groupNoProblems}
+	case GroupChannel:
+		fixed = []string{groupTesting, groupStable, groupOther}
+	}
+
+	rank := make(map[string]int, len(fixed))
+	for i, name := range fixed {
+		rank[name] = i
+	}
+
+	return func(a, b string) bool {
+		return rank[a] < rank[b]
+	}
+}
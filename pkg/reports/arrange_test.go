@@ -0,0 +1,451 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+var testTime = time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+// testTemplate describes a pull request to arrange. Findings are attached
+// to the first plugin, and times are hours after testTime.
+type testTemplate struct {
+	Number   int
+	Title    string
+	Created  int
+	Updated  int
+	Labels   []string
+	Channels []string
+	Errors   int
+	Warnings int
+	Err      error
+	NoResult bool
+	Reviewer string
+}
+
+func (tt testTemplate) build() *ReportTemplate {
+	p := &plogons.Plogon{
+		Number:  tt.Number,
+		Title:   tt.Title,
+		Created: testTime.Add(time.Duration(tt.Created) * time.Hour),
+		Updated: testTime.Add(time.Duration(tt.Updated) * time.Hour),
+		Labels:  make([]*plogons.PlogonLabel, 0),
+	}
+
+	for _, label := range tt.Labels {
+		p.Labels = append(p.Labels, &plogons.PlogonLabel{Name: label})
+	}
+
+	if tt.Reviewer != "" {
+		p.RequestedReviewers = []string{tt.Reviewer}
+	}
+
+	state := &ReportPlogonValidationState{Err: tt.Err}
+	if tt.Err == nil && !tt.NoResult {
+		state.Result = &plogons.PullRequestValidationResult{
+			Plugins: make([]*plogons.PlogonMetaValidationResult, 0),
+		}
+
+		for _, channel := range tt.Channels {
+			state.Result.Plugins = append(state.Result.Plugins, &plogons.PlogonMetaValidationResult{
+				Channel:  channel,
+				Name:     fmt.Sprintf("Plugin%d", tt.Number),
+				Findings: make([]*plogons.Finding, 0),
+			})
+		}
+
+		for i := 0; i < tt.Errors+tt.Warnings; i++ {
+			severity := plogons.SeverityError
+			if i >= tt.Errors {
+				severity = plogons.SeverityWarning
+			}
+
+			plugin := state.Result.Plugins[0]
+			plugin.Findings = append(plugin.Findings, &plogons.Finding{
+				ID:       "meta.required",
+				Severity: severity,
+				Message:  fmt.Sprintf("Finding %d", i),
+			})
+		}
+	}
+
+	return &ReportTemplate{
+		Plogon:          p,
+		ValidationState: state,
+	}
+}
+
+func buildTemplates(tts []testTemplate) []*ReportTemplate {
+	rts := make([]*ReportTemplate, len(tts))
+	for i, tt := range tts {
+		rts[i] = tt.build()
+	}
+
+	return rts
+}
+
+func numbers(rts []*ReportTemplate) []int {
+	res := make([]int, len(rts))
+	for i, rt := range rts {
+		res[i] = rt.Plogon.Number
+	}
+
+	return res
+}
+
+func TestSortLess(t *testing.T) {
+	tests := []struct {
+		name      string
+		sortBy    string
+		templates []testTemplate
+		expected  []int
+	}{
+		{
+			name:   "updated",
+			sortBy: SortUpdated,
+			templates: []testTemplate{
+				{Number: 1, Updated: 1},
+				{Number: 2, Updated: 3},
+				{Number: 3, Updated: 2},
+			},
+			expected: []int{2, 3, 1},
+		},
+		{
+			name:   "empty falls back to updated",
+			sortBy: "",
+			templates: []testTemplate{
+				{Number: 1, Updated: 1},
+				{Number: 2, Updated: 3},
+			},
+			expected: []int{2, 1},
+		},
+		{
+			name:   "unknown falls back to updated",
+			sortBy: "oldest",
+			templates: []testTemplate{
+				{Number: 1, Updated: 1},
+				{Number: 2, Updated: 3},
+			},
+			expected: []int{2, 1},
+		},
+		{
+			name:   "created",
+			sortBy: SortCreated,
+			templates: []testTemplate{
+				{Number: 1, Created: 2, Updated: 1},
+				{Number: 2, Created: 1, Updated: 3},
+				{Number: 3, Created: 3, Updated: 2},
+			},
+			expected: []int{3, 1, 2},
+		},
+		{
+			name:   "problems by errors then warnings",
+			sortBy: SortProblems,
+			templates: []testTemplate{
+				{Number: 1, Channels: []string{"testing"}},
+				{Number: 2, Channels: []string{"testing"}, Errors: 1, Warnings: 1},
+				{Number: 3, Channels: []string{"testing"}, Warnings: 3},
+				{Number: 4, Channels: []string{"testing"}, Errors: 2},
+				{Number: 5, Channels: []string{"testing"}, Errors: 1, Warnings: 2},
+			},
+			expected: []int{4, 5, 2, 3, 1},
+		},
+		{
+			name:   "problems counts failed validation as an error",
+			sortBy: SortProblems,
+			templates: []testTemplate{
+				{Number: 1, Channels: []string{"testing"}, Warnings: 1},
+				{Number: 2, Err: errors.New("rate limited")},
+				{Number: 3, Channels: []string{"testing"}, Errors: 2},
+				{Number: 4, NoResult: true},
+			},
+			expected: []int{3, 2, 4, 1},
+		},
+		{
+			name:   "problems keeps ties in order",
+			sortBy: SortProblems,
+			templates: []testTemplate{
+				{Number: 1, Channels: []string{"testing"}, Errors: 1},
+				{Number: 2, Channels: []string{"testing"}, Errors: 1},
+				{Number: 3, Channels: []string{"testing"}, Errors: 1},
+			},
+			expected: []int{1, 2, 3},
+		},
+		{
+			name:   "title ignores case",
+			sortBy: SortTitle,
+			templates: []testTemplate{
+				{Number: 1, Title: "[Testing] zodiac helper"},
+				{Number: 2, Title: "[Testing] Aetherometer"},
+				{Number: 3, Title: "[Testing] market board"},
+			},
+			expected: []int{2, 3, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rts := buildTemplates(test.templates)
+			less := sortLess(test.sortBy)
+			sort.SliceStable(rts, func(i, j int) bool {
+				return less(rts[i], rts[j])
+			})
+
+			if actual := numbers(rts); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGroupKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupBy  string
+		template testTemplate
+		expected []string
+	}{
+		{name: "none", groupBy: GroupNone, template: testTemplate{Labels: []string{"bug"}}, expected: []string{""}},
+		{name: "unknown", groupBy: "author", template: testTemplate{Labels: []string{"bug"}}, expected: []string{""}},
+		{name: "label", groupBy: GroupLabel, template: testTemplate{Labels: []string{"new plugin"}}, expected: []string{"new plugin"}},
+		{name: "multiple labels", groupBy: GroupLabel, template: testTemplate{Labels: []string{"new plugin", "needs-fixes"}}, expected: []string{"new plugin", "needs-fixes"}},
+		{name: "no labels", groupBy: GroupLabel, template: testTemplate{}, expected: []string{groupUnlabeled}},
+		{name: "validation error", groupBy: GroupValidation, template: testTemplate{Err: errors.New("rate limited")}, expected: []string{groupValidationFailed}},
+		{name: "no validation result", groupBy: GroupValidation, template: testTemplate{NoResult: true}, expected: []string{groupValidationFailed}},
+		{name: "errors", groupBy: GroupValidation, template: testTemplate{Channels: []string{"testing"}, Errors: 1, Warnings: 1}, expected: []string{groupErrors}},
+		{name: "warnings", groupBy: GroupValidation, template: testTemplate{Channels: []string{"testing"}, Warnings: 1}, expected: []string{groupWarnings}},
+		{name: "no problems", groupBy: GroupValidation, template: testTemplate{Channels: []string{"testing"}}, expected: []string{groupNoProblems}},
+		{name: "testing", groupBy: GroupChannel, template: testTemplate{Channels: []string{"testing"}}, expected: []string{groupTesting}},
+		{name: "stable", groupBy: GroupChannel, template: testTemplate{Channels: []string{"plugins"}}, expected: []string{groupStable}},
+		{name: "both channels", groupBy: GroupChannel, template: testTemplate{Channels: []string{"plugins", "testing"}}, expected: []string{groupStable, groupTesting}},
+		{name: "same channel twice", groupBy: GroupChannel, template: testTemplate{Channels: []string{"testing", "testing"}}, expected: []string{groupTesting}},
+		{name: "unknown channel", groupBy: GroupChannel, template: testTemplate{Channels: []string{"outdated"}}, expected: []string{groupOther}},
+		{name: "no plugins", groupBy: GroupChannel, template: testTemplate{}, expected: []string{groupOther}},
+		{name: "no channel result", groupBy: GroupChannel, template: testTemplate{Err: errors.New("rate limited")}, expected: []string{groupOther}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := groupKeys(test.groupBy)(test.template.build())
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGroupOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupBy  string
+		names    []string
+		expected []string
+	}{
+		{
+			name:     "labels by name with unlabeled last",
+			groupBy:  GroupLabel,
+			names:    []string{"needs-fixes", groupUnlabeled, "Bug", "new plugin"},
+			expected: []string{"Bug", "needs-fixes", "new plugin", groupUnlabeled},
+		},
+		{
+			name:     "unlabeled first",
+			groupBy:  GroupLabel,
+			names:    []string{groupUnlabeled, "bug"},
+			expected: []string{"bug", groupUnlabeled},
+		},
+		{
+			name:     "validation",
+			groupBy:  GroupValidation,
+			names:    []string{groupNoProblems, groupWarnings, groupValidationFailed, groupErrors},
+			expected: []string{groupValidationFailed, groupErrors, groupWarnings, groupNoProblems},
+		},
+		{
+			name:     "channel",
+			groupBy:  GroupChannel,
+			names:    []string{groupOther, groupStable, groupTesting},
+			expected: []string{groupTesting, groupStable, groupOther},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := append([]string{}, test.names...)
+			less := groupOrder(test.groupBy)
+			sort.SliceStable(actual, func(i, j int) bool {
+				return less(actual[i], actual[j])
+			})
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+// describeGroups summarizes the groups of a digest as one line per group,
+// listing the pull requests in each section.
+func describeGroups(d *ReportDigest) []string {
+	res := make([]string, len(d.Groups))
+	for i, g := range d.Groups {
+		changed := make([]*ReportTemplate, len(g.Changed))
+		for j, c := range g.Changed {
+			changed[j] = c.ReportTemplate
+		}
+
+		fixed := make([]*ReportTemplate, len(g.Fixed))
+		for j, f := range g.Fixed {
+			fixed[j] = f.ReportTemplate
+		}
+
+		res[i] = fmt.Sprintf("%s: new %v, changed %v, fixed %v", g.Name, numbers(g.New), numbers(changed), numbers(fixed))
+	}
+
+	return res
+}
+
+func TestArrange(t *testing.T) {
+	tests := []struct {
+		name     string
+		sortBy   string
+		groupBy  string
+		reviewer string
+		new      []testTemplate
+		changed  []testTemplate
+		fixed    []testTemplate
+		expected []string
+	}{
+		{
+			name:    "ungrouped",
+			sortBy:  SortUpdated,
+			groupBy: GroupNone,
+			new: []testTemplate{
+				{Number: 1, Updated: 1},
+				{Number: 2, Updated: 2},
+			},
+			changed: []testTemplate{
+				{Number: 3, Updated: 3},
+			},
+			expected: []string{
+				": new [2 1], changed [3], fixed []",
+			},
+		},
+		{
+			name:    "pull requests with several labels are in each group",
+			sortBy:  SortUpdated,
+			groupBy: GroupLabel,
+			new: []testTemplate{
+				{Number: 1, Updated: 1, Labels: []string{"needs-fixes", "new plugin"}},
+				{Number: 2, Updated: 2},
+				{Number: 3, Updated: 3, Labels: []string{"new plugin"}},
+			},
+			changed: []testTemplate{
+				{Number: 4, Updated: 4, Labels: []string{"needs-fixes"}},
+			},
+			fixed: []testTemplate{
+				{Number: 5, Updated: 5},
+			},
+			expected: []string{
+				"needs-fixes: new [1], changed [4], fixed []",
+				"new plugin: new [3 1], changed [], fixed []",
+				groupUnlabeled + ": new [2], changed [], fixed [5]",
+			},
+		},
+		{
+			name:    "unlabeled group comes last even if it's seen first",
+			sortBy:  SortTitle,
+			groupBy: GroupLabel,
+			new: []testTemplate{
+				{Number: 1, Title: "a"},
+				{Number: 2, Title: "b", Labels: []string{"zzz"}},
+				{Number: 3, Title: "c", Labels: []string{"Aaa"}},
+			},
+			expected: []string{
+				"Aaa: new [3], changed [], fixed []",
+				"zzz: new [2], changed [], fixed []",
+				groupUnlabeled + ": new [1], changed [], fixed []",
+			},
+		},
+		{
+			name:    "pull requests changing both channels are in each group",
+			sortBy:  SortCreated,
+			groupBy: GroupChannel,
+			new: []testTemplate{
+				{Number: 1, Created: 1, Channels: []string{"plugins", "testing"}},
+				{Number: 2, Created: 2, Channels: []string{"testing"}},
+			},
+			fixed: []testTemplate{
+				{Number: 3, Created: 3, Channels: []string{"plugins"}},
+				{Number: 4, Created: 4, Err: errors.New("rate limited")},
+			},
+			expected: []string{
+				groupTesting + ": new [2 1], changed [], fixed []",
+				groupStable + ": new [1], changed [], fixed [3]",
+				groupOther + ": new [], changed [], fixed [4]",
+			},
+		},
+		{
+			name:    "validation groups are sorted by problems",
+			sortBy:  SortProblems,
+			groupBy: GroupValidation,
+			new: []testTemplate{
+				{Number: 1, Channels: []string{"testing"}},
+				{Number: 2, Channels: []string{"testing"}, Errors: 1},
+				{Number: 3, Channels: []string{"testing"}, Warnings: 1},
+				{Number: 4, Channels: []string{"testing"}, Errors: 2},
+				{Number: 5, Err: errors.New("rate limited")},
+			},
+			expected: []string{
+				groupValidationFailed + ": new [5], changed [], fixed []",
+				groupErrors + ": new [4 2], changed [], fixed []",
+				groupWarnings + ": new [3], changed [], fixed []",
+				groupNoProblems + ": new [1], changed [], fixed []",
+			},
+		},
+		{
+			name:     "requested reviews come first within each group",
+			sortBy:   SortUpdated,
+			groupBy:  GroupLabel,
+			reviewer: "reviewer",
+			new: []testTemplate{
+				{Number: 1, Updated: 1, Labels: []string{"new plugin"}, Reviewer: "Reviewer"},
+				{Number: 2, Updated: 2, Labels: []string{"new plugin"}},
+				{Number: 3, Updated: 3},
+			},
+			expected: []string{
+				"new plugin: new [1 2], changed [], fixed []",
+				groupUnlabeled + ": new [3], changed [], fixed []",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &ReportDigest{New: buildTemplates(test.new)}
+			for _, rt := range buildTemplates(test.changed) {
+				d.Changed = append(d.Changed, &ReportChanged{ReportTemplate: rt})
+			}
+
+			for _, rt := range buildTemplates(test.fixed) {
+				d.Fixed = append(d.Fixed, &ReportFixed{ReportTemplate: rt})
+			}
+
+			d.Arrange(test.sortBy, test.groupBy, test.reviewer)
+
+			actual := describeGroups(d)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected groups:\n%s\ngot:\n%s", strings.Join(test.expected, "\n"), strings.Join(actual, "\n"))
+			}
+
+			if d.Reviewer != test.reviewer {
+				t.Errorf("expected reviewer %q, got %q", test.reviewer, d.Reviewer)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package reports
+
+import (
+	"sort"
+	"time"
+
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// BuildDigest compares the current pull requests against a reader's
+// snapshots. Pull requests that were updated after since without any
+// tracked field changing are still included as changed, and pull requests
+// resolved after since are included as resolved. The digest is arranged by
+// last update without grouping until Arrange is called.
+func BuildDigest(snapshots map[int]*ReportSnapshot, reportTemplates []*ReportTemplate, resolved []*plogons.ResolvedPlogon, since time.Time) *ReportDigest {
+	digest := &ReportDigest{}
+
+	open := make(map[int]bool, len(reportTemplates))
+	for _, rt := range reportTemplates {
+		open[rt.Plogon.Number] = true
+
+		snapshot, ok := snapshots[rt.Plogon.Number]
+		if !ok {
+			digest.New = append(digest.New, rt)
+			continue
+		}
+
+		current := newReportSnapshot(rt, snapshot)
+
+		changes := make([]*ReportFieldChange, 0)
+		if current.Title != snapshot.Title {
+			changes = append(changes, &ReportFieldChange{
+				Field: "Title",
+				From:  snapshot.Title,
+				To:    current.Title,
+			})
+		}
+
+		if current.HeadSHA != snapshot.HeadSHA {
+			changes = append(changes, &ReportFieldChange{
+				Field: "Head commit",
+				From:  shortSHA(snapshot.HeadSHA),
+				To:    shortSHA(current.HeadSHA),
+			})
+		}
+
+		labelsAdded, labelsRemoved := diffStrings(snapshot.Labels, current.Labels)
+		if len(labelsAdded) != 0 || len(labelsRemoved) != 0 {
+			changes = append(changes, &ReportFieldChange{
+				Field:   "Labels",
+				Added:   labelsAdded,
+				Removed: labelsRemoved,
+			})
+		}
+
+		// Fixed problems get their own section, so only new problems are
+		// reported as a change
+		problemsAdded, problemsFixed := diffStrings(snapshot.Problems, current.Problems)
+		if len(problemsAdded) != 0 {
+			changes = append(changes, &ReportFieldChange{
+				Field: "Problems",
+				Added: problemsAdded,
+			})
+		}
+
+		if len(changes) != 0 || (!since.IsZero() && rt.Plogon.Updated.After(since)) {
+			digest.Changed = append(digest.Changed, &ReportChanged{
+				ReportTemplate: rt,
+				Changes:        changes,
+			})
+		}
+
+		if len(problemsFixed) != 0 {
+			digest.Fixed = append(digest.Fixed, &ReportFixed{
+				ReportTemplate: rt,
+				Fixed:          problemsFixed,
+			})
+		}
+	}
+
+	if !since.IsZero() {
+		for _, r := range resolved {
+			if !r.Resolved.After(since) {
+				continue
+			}
+
+			_, watched := snapshots[r.Number]
+			digest.Resolved = append(digest.Resolved, &ReportResolved{
+				ResolvedPlogon: r,
+				Watched:        watched,
+			})
+			open[r.Number] = true
+		}
+	}
+
+	// Anything else that disappeared since the last report is still
+	// reported, even though we don't know how it was resolved
+	for number, snapshot := range snapshots {
+		if open[number] {
+			continue
+		}
+
+		digest.Resolved = append(digest.Resolved, &ReportResolved{
+			ResolvedPlogon: &plogons.ResolvedPlogon{
+				Plogon: &plogons.Plogon{
+					Number: snapshot.Number,
+					Title:  snapshot.Title,
+					URL:    snapshot.URL,
+				},
+				State: "closed",
+			},
+			Watched: true,
+		})
+	}
+
+	sort.Slice(digest.Resolved, func(i, j int) bool {
+		return digest.Resolved[i].Number < digest.Resolved[j].Number
+	})
+
+	digest.Arrange(SortUpdated, GroupNone, "")
+
+	return digest
+}
+
+// PrioritizeReviewer moves the pull requests awaiting a review from the
+// provided GitHub user to the top of each section, keeping the order of the
+// rest.
+func (d *ReportDigest) PrioritizeReviewer(login string) {
+	d.Reviewer = login
+
+	sort.SliceStable(d.New, func(i, j int) bool {
+		return d.New[i].Plogon.NeedsReviewFrom(login) && !d.New[j].Plogon.NeedsReviewFrom(login)
+	})
+
+	sort.SliceStable(d.Changed, func(i, j int) bool {
+		return d.Changed[i].Plogon.NeedsReviewFrom(login) && !d.Changed[j].Plogon.NeedsReviewFrom(login)
+	})
+
+	sort.SliceStable(d.Fixed, func(i, j int) bool {
+		return d.Fixed[i].Plogon.NeedsReviewFrom(login) && !d.Fixed[j].Plogon.NeedsReviewFrom(login)
+	})
+}
+
+func (d *ReportDigest) Empty() bool {
+	return len(d.New) == 0 && len(d.Changed) == 0 && len(d.Fixed) == 0 && len(d.Resolved) == 0
+}
+
+// newReportSnapshot captures the current state of a pull request. If it
+// couldn't be validated, the problems are carried over from the previous
+// snapshot, if there is one, so that they aren't reported as fixed and then
+// as new again once validation succeeds.
+func newReportSnapshot(rt *ReportTemplate, previous *ReportSnapshot) *ReportSnapshot {
+	labels := make([]string, len(rt.Plogon.Labels))
+	for i, label := range rt.Plogon.Labels {
+		labels[i] = label.Name
+	}
+
+	problems := make([]string, 0)
+	if rt.ValidationState.Err == nil {
+		problems = rt.ValidationState.Result.Problems()
+	} else if previous != nil && previous.Problems != nil {
+		problems = previous.Problems
+	}
+
+	return &ReportSnapshot{
+		Number:   rt.Plogon.Number,
+		Title:    rt.Plogon.Title,
+		URL:      rt.Plogon.URL,
+		HeadSHA:  rt.Plogon.HeadSHA,
+		Labels:   labels,
+		Problems: problems,
+	}
+}
+
+// diffStrings returns the elements that are only in b, and the elements that
+// are only in a.
+func diffStrings(a, b []string) ([]string, []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+
+	added := make([]string, 0)
+	for _, s := range b {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+
+	removed := make([]string, 0)
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
@@ -1,17 +1,17 @@
 package reports
 
 import (
-	"bytes"
 	"hash/fnv"
-	"io"
 	"log"
-	"text/template"
 	"time"
 
 	"github.com/jackc/pgx"
 	"github.com/karashiiro/operator/pkg/html"
 	"github.com/karashiiro/operator/pkg/outlook"
+	"github.com/karashiiro/operator/pkg/pullrequests"
 	"github.com/karashiiro/operator/pkg/repos/plogons"
+	"github.com/karashiiro/operator/pkg/threading"
+	"github.com/karashiiro/operator/pkg/unsubscribe"
 )
 
 type ReportJob struct {
@@ -47,9 +47,9 @@ func (j *ReportJob) Execute() {
 
 	var reportTemplates []*ReportTemplate
 	for rows.Next() {
-		// Process all open pull requests
+		// Load all open pull requests
 		if reportTemplates == nil {
-			reportTemplates, err = GetPlogonReportTemplates()
+			reportTemplates, err = GetReportTemplates(reportConn)
 			if err != nil {
 				log.Printf("Failed to retrieve plogons: %v\n", err)
 				return
@@ -61,39 +61,42 @@ func (j *ReportJob) Execute() {
 		var readerEmail string
 		var readerGithub *string
 		var readerLastSent *time.Time
-		err := rows.Scan(&readerId, &readerEmail, &readerGithub, &readerLastSent)
+		var readerGreenCIOnly bool
+		var readerSort, readerGroup *string
+		err := rows.Scan(&readerId, &readerEmail, &readerGithub, &readerLastSent, &readerGreenCIOnly, &readerSort, &readerGroup)
 		if err != nil {
 			log.Printf("Unable to scan reader row: %v\n", err)
 			continue
 		}
 
-		// Filter the updates since this reader's last email
+		// Compare the current pull requests against what this reader
+		// was last sent
 		ref := time.Time{}
 		if readerLastSent != nil {
 			ref = *readerLastSent
 		}
 
-		// Figure out the size of the array we need so we can allocate
-		// it all at once
-		sinceLastEmail := 0
-		for _, rt := range reportTemplates {
-			if ref.IsZero() || rt.Plogon.Updated.After(ref) {
-				sinceLastEmail++
-			}
+		snapshots, err := getReportSnapshots(reportConn, readerId)
+		if err != nil {
+			log.Printf("Unable to retrieve report snapshots: %v\n", err)
+			continue
 		}
 
-		// Filter the stuff
-		plogonsFiltered := make([]*ReportTemplate, sinceLastEmail)
-		plogonsFilteredIdx := 0
-		for _, rt := range reportTemplates {
-			if ref.IsZero() || rt.Plogon.Updated.After(ref) {
-				plogonsFiltered[plogonsFilteredIdx] = rt
-				plogonsFilteredIdx++
-			}
+		resolved, err := getResolved(reportConn, ref)
+		if err != nil {
+			log.Printf("Failed to retrieve resolved plogons: %v\n", err)
+		}
+
+		readerTemplates := reportTemplates
+		if readerGreenCIOnly {
+			readerTemplates = filterGreenCI(reportTemplates, snapshots)
 		}
 
+		digest := BuildDigest(snapshots, readerTemplates, resolved, ref)
+		digest.Arrange(valueOrEmpty(readerSort), valueOrEmpty(readerGroup), valueOrEmpty(readerGithub))
+
 		// If the result has no data, don't send an email for this interval
-		if plogonsFilteredIdx == 0 {
+		if digest.Empty() {
 			log.Println("Reader has no updates, skipping this interval")
 
 			_, err := storeReportLogSkipped(reportConn, readerId)
@@ -105,25 +108,61 @@ func (j *ReportJob) Execute() {
 		}
 
 		// Send the email
-		var readerMessage bytes.Buffer
-		err = buildTemplate(&readerMessage, plogonsFiltered)
+		readerMessage, err := BuildTemplate(digest)
 		if err != nil {
 			log.Printf("Failed to build template: %v\n", err)
 			continue
 		}
 
+		// Reserve the report ID ahead of time so it can be embedded in the
+		// Message-ID, and thread the report onto the reader's previous ones
+		reportId, err := nextReportId(reportConn)
+		if err != nil {
+			log.Printf("Unable to reserve report ID: %v\n", err)
+			continue
+		}
+
+		headers := unsubscribe.Headers(readerId)
+		messageId := threading.MessageID(readerId, reportId)
+		headers.Set("Message-Id", messageId)
+
+		rootMessageId, lastMessageId, err := getReportThread(reportConn, readerId)
+		if err != nil {
+			log.Printf("Unable to retrieve previous reports: %v\n", err)
+		} else if lastMessageId != nil {
+			references := *lastMessageId
+			if rootMessageId != nil && *rootMessageId != *lastMessageId {
+				references = *rootMessageId + " " + references
+			}
+
+			headers.Set("In-Reply-To", *lastMessageId)
+			headers.Set("References", references)
+		}
+
 		log.Printf("Sending email to %s\n", readerEmail)
-		err = outlook.SendEmail(readerEmail, "Updated Dalamud Plugin Pull Requests", readerMessage.String())
+		err = outlook.Send(&outlook.Message{
+			To:      readerEmail,
+			Subject: "Updated Dalamud Plugin Pull Requests",
+			HTML:    readerMessage.HTML,
+			Text:    readerMessage.Text,
+			Headers: headers,
+		})
 		if err != nil {
 			log.Printf("Unable to send mail: %v\n", err)
 			continue
 		}
 
-		_, err = storeReportLog(reportConn, readerId)
+		_, err = storeReportLog(reportConn, reportId, readerId, messageId)
 		if err != nil {
 			log.Printf("Unable to store report log: %v\n", err)
 			continue
 		}
+
+		err = storeReportSnapshots(reportConn, readerId, readerTemplates, snapshots)
+		if err != nil {
+			log.Printf("Unable to store report snapshots: %v\n", err)
+			continue
+		}
 	}
 
 	if rows.Err() != nil {
@@ -147,63 +186,50 @@ func (j *ReportJob) Key() int {
 	return int(h.Sum32())
 }
 
-func GetPlogonReportTemplates() ([]*ReportTemplate, error) {
-	// Retrieve all open pull requests
-	plogonList, plogonPRs, err := plogons.GetPlogons()
-	if err != nil {
-		return nil, err
+// getResolved returns the pull requests resolved after the provided time.
+// Nothing is returned for the zero time, since there is no previous report
+// to compare against.
+func getResolved(conn *pgx.Conn, since time.Time) ([]*plogons.ResolvedPlogon, error) {
+	if since.IsZero() {
+		return nil, nil
 	}
 
-	// Get the validation states of all open pull requests
-	plogonValidation := make([]*ReportPlogonValidationState, len(plogonList))
-	for i, pr := range plogonPRs {
-		log.Printf("Validating pull request #%d\n", pr.GetNumber())
-		res, err := plogons.ValidatePullRequest(pr)
-		plogonValidation[i] = &ReportPlogonValidationState{
-			Result: res,
-			Err:    err,
-		}
-	}
+	return pullrequests.ListResolved(conn, since)
+}
 
-	// Zip the two slices so we can enumerate them together
-	plogonTemplates := make([]*ReportTemplate, len(plogonList))
-	for i, p := range plogonList {
-		plogonTemplates[i] = &ReportTemplate{
-			Plogon:          p,
-			ValidationState: plogonValidation[i],
+// filterGreenCI returns the pull requests whose checks all passed. The other
+// pull requests are removed from the snapshots too, so that they're reported
+// as new once their checks pass rather than as resolved in the meantime.
+func filterGreenCI(reportTemplates []*ReportTemplate, snapshots map[int]*ReportSnapshot) []*ReportTemplate {
+	green := make([]*ReportTemplate, 0, len(reportTemplates))
+	for _, rt := range reportTemplates {
+		if rt.Plogon.CIState() != plogons.CIPass {
+			delete(snapshots, rt.Plogon.Number)
+			continue
 		}
+
+		green = append(green, rt)
 	}
 
-	return plogonTemplates, nil
+	return green
 }
 
-func buildTemplate(w io.Writer, reportTemplates []*ReportTemplate) error {
-	// Build the HTML template
-	t, err := template.New("report.gohtml").Funcs(template.FuncMap{
-		"formatTime": func(t time.Time) string {
-			return t.Format(time.RFC822)
-		},
-	}).ParseFS(html.Files, "report.gohtml", "report-problems.gohtml")
-	if err != nil {
-		return err
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
 	}
 
-	// Execute the template
-	err = t.Execute(w, struct {
-		PlogonStates []*ReportTemplate
-	}{
-		PlogonStates: reportTemplates,
-	})
-	if err != nil {
-		return err
-	}
+	return *s
+}
 
-	return nil
+func BuildTemplate(digest *ReportDigest) (*html.Body, error) {
+	return html.Render(digest, "report", "report-problems")
 }
 
 func getReadersToNotify(conn *pgx.Conn) (*pgx.Rows, error) {
 	return conn.Query(`
-		SELECT Reader.id, Reader.email, Reader.github, max(Report.sent_time)
+		SELECT Reader.id, Reader.email, Reader.github, max(Report.sent_time), Reader.green_ci_only,
+			Reader.report_sort, Reader.report_group
 		FROM Reader
 		LEFT JOIN Report
 			ON Reader.id = Report.reader_id
@@ -214,12 +240,44 @@ func getReadersToNotify(conn *pgx.Conn) (*pgx.Rows, error) {
 	`)
 }
 
-func storeReportLog(conn *pgx.Conn, readerId int) (int64, error) {
+func nextReportId(conn *pgx.Conn) (int, error) {
+	var reportId int
+	err := conn.QueryRow(`
+		SELECT nextval(pg_get_serial_sequence('Report', 'id'));
+	`).Scan(&reportId)
+	if err != nil {
+		return 0, err
+	}
+
+	return reportId, nil
+}
+
+// getReportThread returns the Message-IDs of the first and the most recent
+// reports sent to a reader, if any have been sent.
+func getReportThread(conn *pgx.Conn, readerId int) (*string, *string, error) {
+	var root, last *string
+	err := conn.QueryRow(`
+		SELECT
+			(SELECT message_id FROM Report
+				WHERE reader_id = $1 AND message_id IS NOT NULL
+				ORDER BY sent_time ASC LIMIT 1),
+			(SELECT message_id FROM Report
+				WHERE reader_id = $1 AND message_id IS NOT NULL
+				ORDER BY sent_time DESC LIMIT 1);
+	`, readerId).Scan(&root, &last)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, last, nil
+}
+
+func storeReportLog(conn *pgx.Conn, reportId int, readerId int, messageId string) (int64, error) {
 	tag, err := conn.Exec(`
-		INSERT INTO Report (sent_time, reader_id, skipped)
+		INSERT INTO Report (id, sent_time, reader_id, skipped, message_id)
 		VALUES
-			(now(), $1, FALSE);
-	`, readerId)
+			($1, now(), $2, FALSE, $3);
+	`, reportId, readerId, messageId)
 	if err != nil {
 		return 0, err
 	}
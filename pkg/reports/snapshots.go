@@ -0,0 +1,63 @@
+package reports
+
+import (
+	"github.com/jackc/pgx"
+)
+
+func getReportSnapshots(conn *pgx.Conn, readerId int) (map[int]*ReportSnapshot, error) {
+	rows, err := conn.Query(`
+		SELECT pr_number, title, url, head_sha, labels, problems
+		FROM ReportSnapshot
+		WHERE reader_id = $1;
+	`, readerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[int]*ReportSnapshot)
+	for rows.Next() {
+		s := &ReportSnapshot{}
+		err := rows.Scan(&s.Number, &s.Title, &s.URL, &s.HeadSHA, &s.Labels, &s.Problems)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots[s.Number] = s
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return snapshots, nil
+}
+
+// storeReportSnapshots replaces a reader's snapshots with the current state
+// of all open pull requests, starting from their previous snapshots.
+func storeReportSnapshots(conn *pgx.Conn, readerId int, reportTemplates []*ReportTemplate, previous map[int]*ReportSnapshot) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM ReportSnapshot WHERE reader_id = $1;", readerId)
+	if err != nil {
+		return err
+	}
+
+	for _, rt := range reportTemplates {
+		s := newReportSnapshot(rt, previous[rt.Plogon.Number])
+		_, err = tx.Exec(`
+			INSERT INTO ReportSnapshot (reader_id, pr_number, title, url, head_sha, labels, problems)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7);
+		`, readerId, s.Number, s.Title, s.URL, s.HeadSHA, s.Labels, s.Problems)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,171 @@
+package reports
+
+import (
+	"hash/fnv"
+	"log"
+	"sort"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/outlook"
+	"github.com/karashiiro/operator/pkg/unsubscribe"
+)
+
+// StaleJob emails the stale pull requests to the readers who opted into the
+// stale queue digest, at the interval each of them chose.
+type StaleJob struct {
+	Pool *pgx.ConnPool
+}
+
+func (j *StaleJob) Execute() {
+	conn, err := j.Pool.Acquire()
+	if err != nil {
+		log.Printf("Failed to acquire database connection: %v\n", err)
+		return
+	}
+	defer j.Pool.Release(conn)
+
+	readers, err := getStaleReadersToNotify(conn)
+	if err != nil {
+		log.Printf("Unable to retrieve readers: %v\n", err)
+		return
+	}
+
+	if len(readers) == 0 {
+		return
+	}
+
+	log.Println("Checking for stale plugin pull requests")
+
+	reportTemplates, err := GetReportTemplates(conn)
+	if err != nil {
+		log.Printf("Failed to retrieve plogons: %v\n", err)
+		return
+	}
+
+	stale := make([]*ReportTemplate, 0)
+	for _, rt := range reportTemplates {
+		if rt.Plogon.Stale != "" {
+			stale = append(stale, rt)
+		}
+	}
+
+	// Nobody needs a nudge if nothing is stale, so this is checked again
+	// next time instead
+	if len(stale) == 0 {
+		log.Println("No stale pull requests found")
+		return
+	}
+
+	// The pull requests that have been idle the longest come first
+	sort.SliceStable(stale, func(i, k int) bool {
+		return stale[i].Plogon.StaleFor > stale[k].Plogon.StaleFor
+	})
+
+	for _, r := range readers {
+		digest := &StaleDigest{
+			Stale: append([]*ReportTemplate{}, stale...),
+		}
+
+		if r.github != nil {
+			digest.PrioritizeReviewer(*r.github)
+		}
+
+		message, err := BuildStaleTemplate(digest)
+		if err != nil {
+			log.Printf("Failed to build template: %v\n", err)
+			continue
+		}
+
+		log.Printf("Sending stale queue email to %s\n", r.email)
+		err = outlook.Send(&outlook.Message{
+			To:      r.email,
+			Subject: "Stale Dalamud Plugin Pull Requests",
+			HTML:    message.HTML,
+			Text:    message.Text,
+			Headers: unsubscribe.Headers(r.id),
+		})
+		if err != nil {
+			log.Printf("Unable to send mail: %v\n", err)
+			continue
+		}
+
+		err = storeStaleSent(conn, r.id)
+		if err != nil {
+			log.Printf("Unable to store stale queue log: %v\n", err)
+			continue
+		}
+	}
+}
+
+func (j *StaleJob) Description() string {
+	return "StaleJob"
+}
+
+func (j *StaleJob) Key() int {
+	h := fnv.New32a()
+	_, err := h.Write([]byte(j.Description()))
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+
+	return int(h.Sum32())
+}
+
+// PrioritizeReviewer moves the pull requests awaiting a review from the
+// provided GitHub user to the top, keeping the order of the rest.
+func (d *StaleDigest) PrioritizeReviewer(login string) {
+	d.Reviewer = login
+
+	sort.SliceStable(d.Stale, func(i, j int) bool {
+		return d.Stale[i].Plogon.NeedsReviewFrom(login) && !d.Stale[j].Plogon.NeedsReviewFrom(login)
+	})
+}
+
+func BuildStaleTemplate(digest *StaleDigest) (*html.Body, error) {
+	return html.Render(digest, "stale")
+}
+
+type staleReader struct {
+	id     int
+	email  string
+	github *string
+}
+
+// getStaleReadersToNotify returns the readers who opted into the stale queue
+// digest and are due to receive it.
+func getStaleReadersToNotify(conn *pgx.Conn) ([]*staleReader, error) {
+	rows, err := conn.Query(`
+		SELECT id, email, github
+		FROM Reader
+		WHERE active AND stale_interval IS NOT NULL
+			AND (stale_sent_time IS NULL OR stale_sent_time + stale_interval <= now());
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readers := make([]*staleReader, 0)
+	for rows.Next() {
+		r := &staleReader{}
+		err := rows.Scan(&r.id, &r.email, &r.github)
+		if err != nil {
+			return nil, err
+		}
+
+		readers = append(readers, r)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return readers, nil
+}
+
+func storeStaleSent(conn *pgx.Conn, readerId int) error {
+	_, err := conn.Exec("UPDATE Reader SET stale_sent_time = now() WHERE id = $1;", readerId)
+	return err
+}
@@ -0,0 +1,49 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/pullrequests"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// GetReportTemplates builds the report templates from the stored open pull
+// requests and their most recent validation results, and marks the stale
+// ones. Pull requests that have never been validated are left out until
+// they are.
+func GetReportTemplates(conn *pgx.Conn) ([]*ReportTemplate, error) {
+	prs, err := pullrequests.ListOpen(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	validations, err := pullrequests.GetOpenValidations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	staleRules := plogons.StaleRulesFromEnv()
+	now := time.Now()
+
+	plogonTemplates := make([]*ReportTemplate, 0, len(prs))
+	for _, pr := range prs {
+		v, ok := validations[pr.GetNumber()]
+		if !ok {
+			continue
+		}
+
+		p := pr.Plogon()
+		p.Stale, p.StaleFor = staleRules.Check(p, now)
+
+		plogonTemplates = append(plogonTemplates, &ReportTemplate{
+			Plogon: p,
+			ValidationState: &ReportPlogonValidationState{
+				Result: v.Result,
+				Err:    v.Err,
+			},
+		})
+	}
+
+	return plogonTemplates, nil
+}
@@ -3,7 +3,7 @@ package reports
 import "github.com/karashiiro/operator/pkg/repos/plogons"
 
 type ReportPlogonValidationState struct {
-	Result *plogons.PlogonMetaValidationResult
+	Result *plogons.PullRequestValidationResult
 	Err    error
 }
 
@@ -11,3 +11,72 @@ type ReportTemplate struct {
 	Plogon          *plogons.Plogon
 	ValidationState *ReportPlogonValidationState
 }
+
+// ReportSnapshot is the state of a pull request as of the last report sent
+// to a reader.
+type ReportSnapshot struct {
+	Number   int
+	Title    string
+	URL      string
+	HeadSHA  string
+	Labels   []string
+	Problems []string
+}
+
+// ReportFieldChange describes how a single field of a pull request changed
+// since the last report. List fields use Added and Removed, while scalar
+// fields use From and To.
+type ReportFieldChange struct {
+	Field   string
+	Added   []string
+	Removed []string
+	From    string
+	To      string
+}
+
+type ReportChanged struct {
+	*ReportTemplate
+	Changes []*ReportFieldChange
+}
+
+type ReportFixed struct {
+	*ReportTemplate
+	Fixed []string
+}
+
+// ReportResolved is a pull request that was merged or closed since the last
+// report. Watched is set if the reader had been sent the pull request before.
+type ReportResolved struct {
+	*plogons.ResolvedPlogon
+	Watched bool
+}
+
+// ReportGroup is a named subset of the open pull requests in a digest. The
+// name is empty if the reader doesn't group their reports.
+type ReportGroup struct {
+	Name    string
+	New     []*ReportTemplate
+	Changed []*ReportChanged
+	Fixed   []*ReportFixed
+}
+
+// ReportDigest is the set of differences between the current pull requests
+// and what a reader was last sent. Groups holds the open pull requests as
+// arranged for the reader, and Reviewer is their GitHub username, if it's
+// known.
+type ReportDigest struct {
+	New      []*ReportTemplate
+	Changed  []*ReportChanged
+	Fixed    []*ReportFixed
+	Resolved []*ReportResolved
+	Groups   []*ReportGroup
+	Reviewer string
+}
+
+// StaleDigest is the set of stale pull requests sent to a reader who opted
+// into the stale queue digest. Reviewer is the reader's GitHub username, if
+// it's known.
+type StaleDigest struct {
+	Stale    []*ReportTemplate
+	Reviewer string
+}
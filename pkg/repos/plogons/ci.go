@@ -0,0 +1,149 @@
+package plogons
+
+import (
+	"context"
+
+	"github.com/google/go-github/v44/github"
+)
+
+// ValidationCheck is the name of the check run and the context of the commit
+// status the Operator publishes validation results under. It isn't counted
+// as part of a pull request's CI.
+const ValidationCheck = "operator/validation"
+
+// CIState is the overall outcome of the checks on a commit.
+type CIState string
+
+const (
+	CINone    CIState = ""
+	CIPass    CIState = "pass"
+	CIFail    CIState = "fail"
+	CIPending CIState = "pending"
+)
+
+func (s CIState) String() string {
+	return string(s)
+}
+
+// CICheck is a single commit status or check run.
+type CICheck struct {
+	Name  string
+	State CIState
+	URL   string
+}
+
+// CIStatus is the combined outcome of the commit statuses and check runs on
+// a commit.
+type CIStatus struct {
+	State  CIState
+	Checks []*CICheck
+}
+
+// GetCIStatus retrieves the commit statuses and check runs of a commit and
+// combines them. Any failing check fails the commit, and otherwise any
+// check that hasn't completed leaves it pending.
+func GetCIStatus(sha string) (*CIStatus, error) {
+	client := github.NewClient(nil)
+
+	checks := make([]*CICheck, 0)
+	statusOpts := &github.ListOptions{PerPage: 100}
+	for {
+		combined, res, err := client.Repositories.GetCombinedStatus(context.Background(), Owner, Repo, sha, statusOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, status := range combined.Statuses {
+			if status.GetContext() == ValidationCheck {
+				continue
+			}
+
+			checks = append(checks, &CICheck{
+				Name:  status.GetContext(),
+				State: statusState(status.GetState()),
+				URL:   status.GetTargetURL(),
+			})
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+
+		statusOpts.Page = res.NextPage
+	}
+
+	checkRunOpts := &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		runs, res, err := client.Checks.ListCheckRunsForRef(context.Background(), Owner, Repo, sha, checkRunOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, run := range runs.CheckRuns {
+			if run.GetName() == ValidationCheck {
+				continue
+			}
+
+			checks = append(checks, &CICheck{
+				Name:  run.GetName(),
+				State: checkRunState(run.GetStatus(), run.GetConclusion()),
+				URL:   run.GetHTMLURL(),
+			})
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+
+		checkRunOpts.Page = res.NextPage
+	}
+
+	return &CIStatus{
+		State:  combineCIStates(checks),
+		Checks: checks,
+	}, nil
+}
+
+func statusState(state string) CIState {
+	switch state {
+	case "success":
+		return CIPass
+	case "failure", "error":
+		return CIFail
+	default:
+		return CIPending
+	}
+}
+
+func checkRunState(status, conclusion string) CIState {
+	if status != "completed" {
+		return CIPending
+	}
+
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return CIPass
+	default:
+		return CIFail
+	}
+}
+
+func combineCIStates(checks []*CICheck) CIState {
+	state := CINone
+	for _, check := range checks {
+		switch check.State {
+		case CIFail:
+			return CIFail
+		case CIPending:
+			state = CIPending
+		case CIPass:
+			if state == CINone {
+				state = CIPass
+			}
+		}
+	}
+
+	return state
+}
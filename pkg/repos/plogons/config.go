@@ -0,0 +1,75 @@
+package plogons
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+var defaultCategoryTags = []string{"other", "jobs", "ui", "minigames", "inventory", "sound", "social", "utility"}
+
+var defaultImageHosts = []string{"raw.githubusercontent.com", "github.com", "user-images.githubusercontent.com", "i.imgur.com"}
+
+// currentAPILevel returns the Dalamud API level plugins are expected to
+// target, if one is configured.
+func currentAPILevel() (int, bool) {
+	level, err := strconv.Atoi(os.Getenv("OPERATOR_DALAMUD_API_LEVEL"))
+	if err != nil {
+		return 0, false
+	}
+
+	return level, true
+}
+
+// categoryTags returns the allowed values of CategoryTags.
+func categoryTags() []string {
+	return envList("OPERATOR_CATEGORY_TAGS", defaultCategoryTags)
+}
+
+// imageHosts returns the hosts icons and images may be served from.
+// Subdomains of these hosts are allowed as well.
+func imageHosts() []string {
+	return envList("OPERATOR_IMAGE_HOSTS", defaultImageHosts)
+}
+
+// maxImageSize returns the largest icon or image that will be downloaded,
+// in bytes.
+func maxImageSize() int64 {
+	return int64(envInt("OPERATOR_MAX_IMAGE_SIZE", 10<<20))
+}
+
+// iconSizeLimits returns the minimum and maximum width of a (square) icon.
+func iconSizeLimits() (int, int) {
+	return envInt("OPERATOR_ICON_MIN_SIZE", 64), envInt("OPERATOR_ICON_MAX_SIZE", 512)
+}
+
+// imageSizeLimits returns the maximum width and height of an image.
+func imageSizeLimits() (int, int) {
+	return envInt("OPERATOR_IMAGE_MAX_WIDTH", 2560), envInt("OPERATOR_IMAGE_MAX_HEIGHT", 1440)
+}
+
+func envInt(key string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return n
+}
+
+func envList(key string, def []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,202 @@
+package plogons
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+// Finding is a single result produced by a validation rule. Field and File
+// are optional, and refer to the manifest field and the file in the pull
+// request the finding came from.
+type Finding struct {
+	ID       string
+	Severity Severity
+	Message  string
+	Field    string
+	File     string
+}
+
+// PullRequestValidationResult holds the validation results of every plugin
+// a pull request touches.
+type PullRequestValidationResult struct {
+	Plugins []*PlogonMetaValidationResult
+}
+
+// Problems lists the problems of all plugins in the pull request. If the
+// pull request touches more than one plugin, each problem is prefixed with
+// the directory of the plugin it belongs to.
+func (r *PullRequestValidationResult) Problems() []string {
+	problems := make([]string, 0)
+	for _, p := range r.Plugins {
+		for _, problem := range p.Problems() {
+			if len(r.Plugins) > 1 {
+				problem = p.Dir() + ": " + problem
+			}
+
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+// HasErrors returns true if any plugin has an error finding or failed to be
+// validated.
+func (r *PullRequestValidationResult) HasErrors() bool {
+	for _, p := range r.Plugins {
+		if p.Err != nil || p.HasErrors() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count returns the number of findings with the provided severity across
+// all plugins.
+func (r *PullRequestValidationResult) Count(severity Severity) int {
+	n := 0
+	for _, p := range r.Plugins {
+		n += p.Count(severity)
+	}
+
+	return n
+}
+
+// Summary describes the validation outcome in a single short line, such as
+// "2 errors, 1 warning".
+func (r *PullRequestValidationResult) Summary() string {
+	parts := make([]string, 0)
+	for _, p := range r.Plugins {
+		if p.Err != nil {
+			parts = append(parts, fmt.Sprintf("%s could not be validated", p.Dir()))
+		}
+	}
+
+	for _, severity := range []Severity{SeverityError, SeverityWarning} {
+		n := r.Count(severity)
+		if n == 1 {
+			parts = append(parts, fmt.Sprintf("1 %s", severity))
+		} else if n > 1 {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, severity))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "No problems found"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Hash returns a digest of every plugin's findings and errors, which only
+// changes when the validation outcome does.
+func (r *PullRequestValidationResult) Hash() string {
+	h := sha256.New()
+	for _, p := range r.Plugins {
+		fmt.Fprintf(h, "%s\x00%v\x00", p.Dir(), p.Err)
+		for _, f := range p.Findings {
+			fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s\x00", f.ID, f.Severity, f.Message, f.Field, f.File)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// PlogonMetaValidationResult is the validation result of a single plugin.
+// Err is set if the plugin could not be validated at all.
+type PlogonMetaValidationResult struct {
+	Channel  string
+	Name     string
+	Findings []*Finding
+	Err      error
+}
+
+// Dir returns the directory of the plugin in the repository.
+func (r *PlogonMetaValidationResult) Dir() string {
+	return r.Channel + "/" + r.Name
+}
+
+// Problems lists the messages of all error and warning findings. The
+// messages are stable, so they can be compared across runs.
+func (r *PlogonMetaValidationResult) Problems() []string {
+	problems := make([]string, 0)
+	if r.Err != nil {
+		problems = append(problems, fmt.Sprintf("error: %v", r.Err))
+	}
+
+	for _, f := range r.ProblemFindings() {
+		problems = append(problems, f.Message)
+	}
+
+	return problems
+}
+
+// ProblemFindings returns the error and warning findings. Info findings
+// describe the plugin rather than anything wrong with it, so they aren't
+// problems.
+func (r *PlogonMetaValidationResult) ProblemFindings() []*Finding {
+	findings := make([]*Finding, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		if f.Severity != SeverityInfo {
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// HasErrors returns true if any finding has error severity.
+func (r *PlogonMetaValidationResult) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count returns the number of findings with the provided severity.
+func (r *PlogonMetaValidationResult) Count(severity Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+
+	return n
+}
+
+// sortFindings orders findings from the most to the least severe, keeping
+// the order the rules produced them in otherwise.
+func sortFindings(findings []*Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity < findings[j].Severity
+	})
+}
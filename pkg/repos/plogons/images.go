@@ -0,0 +1,126 @@
+package plogons
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+)
+
+// imageInfo describes an image downloaded from a manifest URL.
+type imageInfo struct {
+	// Format is "png" or "jpeg", as detected from the image's magic bytes
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+var imageClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+
+		return checkImageURL(req.URL)
+	},
+}
+
+// checkImageURL checks that an image URL uses https and points to one of the
+// allowed image hosts.
+func checkImageURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("%s is not an https URL", u.Redacted())
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range imageHosts() {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is not an allowed image host", host)
+}
+
+// fetchImage downloads the image at the provided URL and decodes its format
+// and dimensions. Only PNG and JPEG images are accepted.
+func fetchImage(rawURL string) (*imageInfo, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkImageURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := imageClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	maxSize := maxImageSize()
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) > maxSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxSize)
+	}
+
+	info := &imageInfo{}
+	info.ContentType, _, _ = mime.ParseMediaType(res.Header.Get("Content-Type"))
+
+	switch {
+	case bytes.HasPrefix(buf, pngMagic):
+		info.Format = "png"
+	case bytes.HasPrefix(buf, jpegMagic):
+		info.Format = "jpeg"
+	default:
+		return nil, fmt.Errorf("content is not a PNG or JPEG image (served as %q)", info.ContentType)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s image: %v", info.Format, err)
+	}
+
+	if format != info.Format {
+		return nil, fmt.Errorf("could not decode %s image", info.Format)
+	}
+
+	info.Width = config.Width
+	info.Height = config.Height
+
+	return info, nil
+}
+
+// contentTypeMatches returns true if the image was served with the content
+// type of its actual format.
+func (i *imageInfo) contentTypeMatches() bool {
+	return i.ContentType == "image/"+i.Format
+}
@@ -0,0 +1,297 @@
+package plogons
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// parseManifest parses a plugin manifest. Byte order marks are detected and
+// removed, and comments and trailing commas are tolerated, but each of these
+// is reported as a finding. Syntax errors, duplicate keys, and anything
+// outside of the manifest object are reported with their line and column.
+// The JSON data the manifest was decoded from is returned along with it.
+func parseManifest(buf []byte, file string) (*PlogonMeta, []*Finding, []byte, error) {
+	findings := make([]*Finding, 0)
+	finding := func(severity Severity, message string) {
+		findings = append(findings, &Finding{
+			ID:       "manifest.parse",
+			Severity: severity,
+			Message:  message,
+			File:     file,
+		})
+	}
+
+	data, encoding := decodeBOM(buf)
+	if encoding != "" {
+		finding(SeverityWarning, fmt.Sprintf("Manifest starts with a %s byte order mark", encoding))
+	}
+
+	data, leniencies := stripLeniencies(data)
+	for _, l := range leniencies {
+		line, col := position(data, l.offset)
+		finding(SeverityWarning, fmt.Sprintf("Manifest contains a %s at line %d, column %d", l.kind, line, col))
+	}
+
+	duplicates, err := checkManifestStructure(data)
+	if err != nil {
+		return nil, nil, nil, withPosition(file, data, err)
+	}
+
+	for _, d := range duplicates {
+		line, col := position(data, d.offset)
+		finding(SeverityError, fmt.Sprintf("Manifest key %q is duplicated at line %d, column %d", d.key, line, col))
+	}
+
+	meta := &PlogonMeta{}
+	err = json.Unmarshal(data, meta)
+	if err != nil {
+		return nil, nil, nil, withPosition(file, data, err)
+	}
+
+	return meta, findings, data, nil
+}
+
+type manifestSyntaxError struct {
+	msg    string
+	offset int64
+}
+
+func (e *manifestSyntaxError) Error() string {
+	return e.msg
+}
+
+type manifestLeniency struct {
+	kind   string
+	offset int64
+}
+
+type duplicateKey struct {
+	key    string
+	offset int64
+}
+
+// decodeBOM removes any byte order mark from the start of the data,
+// converting UTF-16 data to UTF-8. The name of the detected encoding is
+// returned, or an empty string if there was no byte order mark.
+func decodeBOM(buf []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(buf, []byte{0xEF, 0xBB, 0xBF}):
+		return buf[3:], "UTF-8"
+	case bytes.HasPrefix(buf, []byte{0xFF, 0xFE}):
+		return decodeUTF16(buf[2:], false), "UTF-16LE"
+	case bytes.HasPrefix(buf, []byte{0xFE, 0xFF}):
+		return decodeUTF16(buf[2:], true), "UTF-16BE"
+	default:
+		return buf, ""
+	}
+}
+
+func decodeUTF16(buf []byte, bigEndian bool) []byte {
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(buf[2*i])<<8 | uint16(buf[2*i+1])
+		} else {
+			units[i] = uint16(buf[2*i+1])<<8 | uint16(buf[2*i])
+		}
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
+
+// stripLeniencies blanks out comments and trailing commas, which aren't
+// valid JSON but are accepted by the plugin build tooling. They are replaced
+// with whitespace, so that the positions of everything else are unchanged.
+func stripLeniencies(buf []byte) ([]byte, []*manifestLeniency) {
+	data := append([]byte{}, buf...)
+	leniencies := make([]*manifestLeniency, 0)
+
+	blank := func(start, end int) {
+		for i := start; i < end; i++ {
+			if data[i] != '\n' {
+				data[i] = ' '
+			}
+		}
+	}
+
+	// Offset of the last comma that may turn out to be a trailing comma
+	lastComma := -1
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			lastComma = -1
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			end := bytes.IndexByte(data[i:], '\n')
+			if end == -1 {
+				end = len(data) - i
+			}
+
+			leniencies = append(leniencies, &manifestLeniency{kind: "comment", offset: int64(i)})
+			blank(i, i+end)
+			i += end - 1
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end == -1 {
+				end = len(data) - i
+			} else {
+				end += 4
+			}
+
+			leniencies = append(leniencies, &manifestLeniency{kind: "comment", offset: int64(i)})
+			blank(i, i+end)
+			i += end - 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma != -1 {
+				leniencies = append(leniencies, &manifestLeniency{kind: "trailing comma", offset: int64(lastComma)})
+				blank(lastComma, lastComma+1)
+			}
+
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+
+	return data, leniencies
+}
+
+// checkManifestStructure walks the tokens of the manifest, checking that it
+// is a single object and finding any duplicated keys.
+func checkManifestStructure(data []byte) ([]*duplicateKey, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	start := int64(len(data) - len(bytes.TrimLeft(data, " \t\r\n")))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, &manifestSyntaxError{msg: "manifest is not a JSON object", offset: start}
+	}
+
+	type frame struct {
+		object    bool
+		expectKey bool
+		keys      map[string]bool
+	}
+
+	duplicates := make([]*duplicateKey, 0)
+	stack := []*frame{{object: true, expectKey: true, keys: make(map[string]bool)}}
+	for len(stack) > 0 {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		top := stack[len(stack)-1]
+		if top.object && top.expectKey {
+			if delim, ok := tok.(json.Delim); ok && delim == '}' {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			key := tok.(string)
+			if top.keys[key] {
+				// InputOffset points at the separator before the key
+				keyOffset := offset + int64(bytes.IndexByte(data[offset:], '"'))
+				duplicates = append(duplicates, &duplicateKey{key: key, offset: keyOffset})
+			}
+
+			top.keys[key] = true
+			top.expectKey = false
+			continue
+		}
+
+		if top.object {
+			top.expectKey = true
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, &frame{object: true, expectKey: true, keys: make(map[string]bool)})
+		case json.Delim('['):
+			stack = append(stack, &frame{})
+		case json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	// Anything after the manifest object is an error, whether it's another
+	// JSON value or not
+	end := dec.InputOffset()
+	rest := bytes.TrimLeft(data[end:], " \t\r\n")
+	if len(rest) != 0 {
+		offset := int64(len(data) - len(rest))
+		_, err := dec.Token()
+		if err == nil {
+			return nil, &manifestSyntaxError{msg: "manifest contains more than one JSON value", offset: offset}
+		}
+
+		return nil, &manifestSyntaxError{msg: "manifest contains text after the JSON object", offset: offset}
+	}
+
+	return duplicates, nil
+}
+
+// withPosition annotates JSON errors with the file, line and column they
+// occurred at.
+func withPosition(file string, data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var manifestErr *manifestSyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset is just past the offending character
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.As(err, &manifestErr):
+		offset = manifestErr.offset
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		offset = int64(len(data))
+		err = fmt.Errorf("unexpected end of manifest")
+	default:
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	line, col := position(data, offset)
+	return fmt.Errorf("%s:%d:%d: %v", file, line, col, err)
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	} else if offset < 0 {
+		offset = 0
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
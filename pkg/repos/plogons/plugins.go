@@ -0,0 +1,141 @@
+package plogons
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// pluginFiles are the files in a pull request that belong to a single
+// plugin in a single channel.
+type pluginFiles struct {
+	// Channel is the top-level directory of the plugin, either "plugins" for
+	// stable plugins or "testing" for testing plugins
+	Channel string
+	Name    string
+	Files   []*gitdiff.File
+}
+
+// Dir returns the directory the plugin's files are in.
+func (p *pluginFiles) Dir() string {
+	return path.Join(p.Channel, p.Name)
+}
+
+// contains returns true if the provided path is inside the plugin's
+// directory.
+func (p *pluginFiles) contains(filePath string) bool {
+	return strings.HasPrefix(filePath, p.Dir()+"/")
+}
+
+// present lists the paths of the plugin's changed files that still exist
+// in the plugin's directory after the pull request.
+func (p *pluginFiles) present() []string {
+	paths := make([]string, 0)
+	for _, f := range p.Files {
+		if !f.IsDelete && p.contains(f.NewName) {
+			paths = append(paths, f.NewName)
+		}
+	}
+
+	return paths
+}
+
+// Removed returns true if the pull request removes the plugin from its
+// directory, either by deleting its manifest or by moving it elsewhere.
+// Deleting any other file, such as an old image, leaves the plugin in place.
+func (p *pluginFiles) Removed() bool {
+	manifest := path.Join(p.Dir(), p.Name+".json")
+	for _, f := range p.Files {
+		if f.OldName == manifest && (f.IsDelete || !p.contains(f.NewName)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MetaFile returns the path of the plugin's manifest. A changed manifest
+// named after the plugin's directory is preferred, then any other changed
+// manifest. If the manifest wasn't changed (for example, if only the zip was
+// updated), the conventional path of the manifest is returned.
+func (p *pluginFiles) MetaFile() string {
+	conventional := path.Join(p.Dir(), p.Name+".json")
+
+	metaFile := ""
+	for _, filePath := range p.present() {
+		if filePath == conventional {
+			return filePath
+		}
+
+		if metaFile == "" && strings.HasSuffix(filePath, ".json") {
+			metaFile = filePath
+		}
+	}
+
+	if metaFile == "" {
+		return conventional
+	}
+
+	return metaFile
+}
+
+// ZipFile returns the path of the plugin's zip, falling back to the
+// conventional path if the zip wasn't changed.
+func (p *pluginFiles) ZipFile() string {
+	for _, filePath := range p.present() {
+		if strings.HasSuffix(filePath, ".zip") {
+			return filePath
+		}
+	}
+
+	return path.Join(p.Dir(), "latest.zip")
+}
+
+// groupPluginFiles groups the files in a diff by the plugin directory they
+// are in, in the order the plugins first appear. Renamed files belong to
+// both their old and new directories, and deleted files to their old
+// directory. Files outside of plugin directories are ignored.
+func groupPluginFiles(diffFiles []*gitdiff.File) []*pluginFiles {
+	groups := make([]*pluginFiles, 0)
+	byDir := make(map[string]*pluginFiles)
+	add := func(filePath string, f *gitdiff.File) {
+		parts := strings.Split(filePath, "/")
+		if len(parts) < 3 || (parts[0] != "plugins" && parts[0] != "testing") {
+			return
+		}
+
+		dir := path.Join(parts[0], parts[1])
+		group, ok := byDir[dir]
+		if !ok {
+			group = &pluginFiles{
+				Channel: parts[0],
+				Name:    parts[1],
+			}
+
+			byDir[dir] = group
+			groups = append(groups, group)
+		}
+
+		// A file renamed within the same directory is only added once
+		for _, existing := range group.Files {
+			if existing == f {
+				return
+			}
+		}
+
+		group.Files = append(group.Files, f)
+	}
+
+	for _, f := range diffFiles {
+		if f.IsDelete || f.IsRename {
+			add(f.OldName, f)
+		}
+
+		if !f.IsDelete {
+			add(f.NewName, f)
+		}
+	}
+
+	return groups
+}
@@ -6,35 +6,125 @@ import (
 	"github.com/google/go-github/v44/github"
 )
 
+// The repository plugin pull requests are opened against.
+const (
+	Owner = "goatcorp"
+	Repo  = "DalamudPlugins"
+)
+
 func GetPlogons() ([]*Plogon, []*github.PullRequest, error) {
 	// Retrieve all open pull requests
 	client := github.NewClient(nil)
-	plogonPRs, _, err := client.PullRequests.List(context.Background(), "goatcorp", "DalamudPlugins", &github.PullRequestListOptions{
-		State: "open",
-	})
-	if err != nil {
-		return nil, nil, err
+
+	plogonPRs := make([]*github.PullRequest, 0)
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		page, res, err := client.PullRequests.List(context.Background(), Owner, Repo, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plogonPRs = append(plogonPRs, page...)
+		if res.NextPage == 0 {
+			break
+		}
+
+		opts.Page = res.NextPage
 	}
 
 	// Make the plogons :dognosepretty:
 	plogonsPretty := make([]*Plogon, len(plogonPRs))
 	for i, plogon := range plogonPRs {
-		labels := make([]*PlogonLabel, len(plogon.Labels))
-		for j, label := range plogon.Labels {
-			labels[j] = &PlogonLabel{
-				Name:  label.GetName(),
-				Color: label.GetColor(),
-			}
+		plogonsPretty[i] = NewPlogon(plogon)
+	}
+
+	return plogonsPretty, plogonPRs, nil
+}
+
+// GetPullRequest retrieves a single pull request, whether it's open or not.
+func GetPullRequest(number int) (*github.PullRequest, error) {
+	client := github.NewClient(nil)
+	pr, _, err := client.PullRequests.Get(context.Background(), Owner, Repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// GetResolvedBy returns who merged or closed a pull request. Pull requests
+// retrieved individually include who merged them, but who closed them needs
+// to be fetched separately.
+func GetResolvedBy(pr *github.PullRequest) (string, error) {
+	if pr.MergedAt != nil {
+		return pr.GetMergedBy().GetLogin(), nil
+	}
+
+	client := github.NewClient(nil)
+	issue, _, err := client.Issues.Get(context.Background(), Owner, Repo, pr.GetNumber())
+	if err != nil {
+		return "", err
+	}
+
+	return issue.GetClosedBy().GetLogin(), nil
+}
+
+// NewPlogon converts a pull request into its report representation.
+func NewPlogon(plogon *github.PullRequest) *Plogon {
+	labels := make([]*PlogonLabel, len(plogon.Labels))
+	for j, label := range plogon.Labels {
+		labels[j] = &PlogonLabel{
+			Name:  label.GetName(),
+			Color: label.GetColor(),
 		}
+	}
 
-		plogonsPretty[i] = &Plogon{
-			Title:     plogon.GetTitle(),
-			URL:       plogon.GetHTMLURL(),
-			Labels:    labels,
-			Submitter: plogon.User.GetLogin(),
-			Updated:   plogon.GetUpdatedAt(),
+	reviewers := make([]string, 0, len(plogon.RequestedReviewers)+len(plogon.RequestedTeams))
+	for _, user := range plogon.RequestedReviewers {
+		reviewers = append(reviewers, user.GetLogin())
+	}
+
+	for _, team := range plogon.RequestedTeams {
+		reviewers = append(reviewers, Owner+"/"+team.GetSlug())
+	}
+
+	return &Plogon{
+		Number:             plogon.GetNumber(),
+		Title:              plogon.GetTitle(),
+		URL:                plogon.GetHTMLURL(),
+		HeadSHA:            plogon.GetHead().GetSHA(),
+		Labels:             labels,
+		Submitter:          plogon.User.GetLogin(),
+		Created:            plogon.GetCreatedAt(),
+		Updated:            plogon.GetUpdatedAt(),
+		Draft:              plogon.GetDraft(),
+		ReviewState:        ReviewPending,
+		RequestedReviewers: reviewers,
+	}
+}
+
+// GetReviews retrieves all of the reviews submitted on a pull request.
+func GetReviews(number int) ([]*github.PullRequestReview, error) {
+	client := github.NewClient(nil)
+
+	reviews := make([]*github.PullRequestReview, 0)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, res, err := client.PullRequests.ListReviews(context.Background(), Owner, Repo, number, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, page...)
+		if res.NextPage == 0 {
+			break
 		}
+
+		opts.Page = res.NextPage
 	}
 
-	return plogonsPretty, plogonPRs, nil
+	return reviews, nil
 }
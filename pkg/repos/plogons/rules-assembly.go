@@ -0,0 +1,110 @@
+package plogons
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/karashiiro/operator/pkg/dotnet"
+)
+
+// Assemblies provided by Dalamud, whose referenced versions are reported
+var hostAssemblies = []string{"Dalamud", "FFXIVClientStructs", "ImGui.NET"}
+
+func checkAssemblyMetadata(ctx *ValidationContext) []*Finding {
+	if ctx.DLLFile == nil {
+		return nil
+	}
+
+	if ctx.DLLErr != nil {
+		return []*Finding{{
+			ID:       "assembly.metadata",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Unable to read %s: %v", ctx.DLLFile.Name, ctx.DLLErr),
+			File:     ctx.ZipFile,
+		}}
+	}
+
+	assembly, err := dotnet.Read(ctx.DLL)
+	if err != nil {
+		return []*Finding{{
+			ID:       "assembly.metadata",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Unable to read .NET metadata from %s: %v", ctx.DLLFile.Name, err),
+			File:     ctx.ZipFile,
+		}}
+	}
+
+	findings := make([]*Finding, 0)
+	if assembly.Name != ctx.Meta.InternalName {
+		findings = append(findings, &Finding{
+			ID:       "assembly.metadata",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Assembly name %s does not match the internal name", assembly.Name),
+			Field:    "InternalName",
+			File:     ctx.ZipFile,
+		})
+	}
+
+	// The compiled version needs to match one of the manifest versions
+	if ctx.Meta.AssemblyVersion != "" && !versionMatches(ctx.Meta.AssemblyVersion, assembly.Version) &&
+		(ctx.Meta.TestingAssemblyVersion == "" || !versionMatches(ctx.Meta.TestingAssemblyVersion, assembly.Version)) {
+		findings = append(findings, &Finding{
+			ID:       "assembly.metadata",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Assembly version %s does not match the manifest version %s", assembly.Version, ctx.Meta.AssemblyVersion),
+			Field:    "AssemblyVersion",
+			File:     ctx.ZipFile,
+		})
+	}
+
+	for _, name := range hostAssemblies {
+		ref := assembly.Reference(name)
+		if ref == nil {
+			continue
+		}
+
+		findings = append(findings, &Finding{
+			ID:       "assembly.metadata",
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("References %s %s", ref.Name, ref.Version),
+			File:     ctx.ZipFile,
+		})
+	}
+
+	dalamud := assembly.Reference("Dalamud")
+	if dalamud == nil {
+		findings = append(findings, &Finding{
+			ID:       "assembly.metadata",
+			Severity: SeverityError,
+			Message:  "Assembly does not reference Dalamud",
+			File:     ctx.ZipFile,
+		})
+	} else if expected := os.Getenv("OPERATOR_DALAMUD_ASSEMBLY_VERSION"); expected != "" && !versionMatches(expected, dalamud.Version) {
+		findings = append(findings, &Finding{
+			ID:       "assembly.metadata",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Assembly references Dalamud %s instead of %s", dalamud.Version, expected),
+			File:     ctx.ZipFile,
+		})
+	}
+
+	return findings
+}
+
+// versionMatches compares a manifest version against an assembly version.
+// Missing components of the manifest version are treated as 0.
+func versionMatches(version string, assemblyVersion dotnet.Version) bool {
+	components, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+
+	for len(components) < 4 {
+		components = append(components, 0)
+	}
+
+	return components[0] == int(assemblyVersion.Major) &&
+		components[1] == int(assemblyVersion.Minor) &&
+		components[2] == int(assemblyVersion.Build) &&
+		components[3] == int(assemblyVersion.Revision)
+}
@@ -0,0 +1,125 @@
+package plogons
+
+import "fmt"
+
+func checkBaseDiff(ctx *ValidationContext) []*Finding {
+	if ctx.BaseMeta == nil {
+		return []*Finding{{
+			ID:       "base.diff",
+			Severity: SeverityInfo,
+			Message:  "New plugin",
+			File:     ctx.MetaFile,
+		}}
+	}
+
+	base := ctx.BaseMeta
+	meta := ctx.Meta
+
+	changes := []struct {
+		Field   string
+		Changed bool
+		Message string
+	}{
+		{"AssemblyVersion", base.AssemblyVersion != meta.AssemblyVersion, fmt.Sprintf("Version changed from %s to %s", base.AssemblyVersion, meta.AssemblyVersion)},
+		{"DalamudApiLevel", base.DalamudAPILevel != meta.DalamudAPILevel, fmt.Sprintf("API level changed from %d to %d", base.DalamudAPILevel, meta.DalamudAPILevel)},
+		{"Description", base.Description != meta.Description, "Description changed"},
+		{"RepoUrl", base.RepoURL != meta.RepoURL, fmt.Sprintf("Repo URL changed from %s to %s", base.RepoURL, meta.RepoURL)},
+	}
+
+	findings := make([]*Finding, 0)
+	for _, c := range changes {
+		if c.Changed {
+			findings = append(findings, &Finding{
+				ID:       "base.diff",
+				Severity: SeverityInfo,
+				Message:  c.Message,
+				Field:    c.Field,
+				File:     ctx.MetaFile,
+			})
+		}
+	}
+
+	if ctx.BaseFile != "" && ctx.BaseFile != ctx.MetaFile {
+		findings = append(findings, &Finding{
+			ID:       "base.diff",
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("Moved from %s", ctx.BaseFile),
+			File:     ctx.MetaFile,
+		})
+	}
+
+	return findings
+}
+
+func checkBaseVersion(ctx *ValidationContext) []*Finding {
+	if ctx.BaseMeta == nil {
+		return nil
+	}
+
+	baseVersion, err := parseVersion(ctx.BaseMeta.AssemblyVersion)
+	if err != nil {
+		return nil
+	}
+
+	version, err := parseVersion(ctx.Meta.AssemblyVersion)
+	if err != nil || compareVersions(version, baseVersion) > 0 {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "base.version",
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("Version %s did not increase from %s", ctx.Meta.AssemblyVersion, ctx.BaseMeta.AssemblyVersion),
+		Field:    "AssemblyVersion",
+		File:     ctx.MetaFile,
+	}}
+}
+
+func checkBaseAPILevel(ctx *ValidationContext) []*Finding {
+	if ctx.BaseMeta == nil || ctx.Meta.DalamudAPILevel >= ctx.BaseMeta.DalamudAPILevel {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "base.api-level",
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("API level went backwards from %d to %d", ctx.BaseMeta.DalamudAPILevel, ctx.Meta.DalamudAPILevel),
+		Field:    "DalamudApiLevel",
+		File:     ctx.MetaFile,
+	}}
+}
+
+func checkBaseAuthor(ctx *ValidationContext) []*Finding {
+	if ctx.BaseMeta == nil || ctx.Meta.Author == ctx.BaseMeta.Author {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "base.author",
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("Author changed from %s to %s", ctx.BaseMeta.Author, ctx.Meta.Author),
+		Field:    "Author",
+		File:     ctx.MetaFile,
+	}}
+}
+
+// compareVersions compares two parsed versions, treating missing components
+// as 0. It returns a negative number if a < b, 0 if a == b, and a positive
+// number if a > b.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := 0, 0
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			return x - y
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,81 @@
+package plogons
+
+import (
+	"fmt"
+)
+
+func checkIcon(ctx *ValidationContext) []*Finding {
+	finding := func(severity Severity, message string) []*Finding {
+		return []*Finding{{
+			ID:       "meta.icon",
+			Severity: severity,
+			Message:  message,
+			Field:    "IconUrl",
+			File:     ctx.MetaFile,
+		}}
+	}
+
+	if ctx.Meta.IconURL == "" {
+		return finding(SeverityInfo, "No icon set in metadata (may exist regardless)")
+	}
+
+	icon, err := fetchImage(ctx.Meta.IconURL)
+	if err != nil {
+		return finding(SeverityError, fmt.Sprintf("Icon URL does not point to a valid image: %v", err))
+	}
+
+	findings := make([]*Finding, 0)
+	if !icon.contentTypeMatches() {
+		findings = append(findings, finding(SeverityWarning,
+			fmt.Sprintf("Icon is a %s image but is served as %q", icon.Format, icon.ContentType))...)
+	}
+
+	minSize, maxSize := iconSizeLimits()
+	if icon.Width != icon.Height {
+		findings = append(findings, finding(SeverityError,
+			fmt.Sprintf("Icon is not square (%dx%d)", icon.Width, icon.Height))...)
+	} else if icon.Width < minSize || icon.Width > maxSize {
+		findings = append(findings, finding(SeverityError,
+			fmt.Sprintf("Icon is %dx%d, but must be between %dx%d and %dx%d",
+				icon.Width, icon.Height, minSize, minSize, maxSize, maxSize))...)
+	}
+
+	return findings
+}
+
+func checkImages(ctx *ValidationContext) []*Finding {
+	findings := make([]*Finding, 0)
+	finding := func(severity Severity, message string) {
+		findings = append(findings, &Finding{
+			ID:       "meta.images",
+			Severity: severity,
+			Message:  message,
+			Field:    "ImageUrls",
+			File:     ctx.MetaFile,
+		})
+	}
+
+	maxWidth, maxHeight := imageSizeLimits()
+	for i, url := range ctx.Meta.ImageURLs {
+		if url == "" {
+			continue
+		}
+
+		img, err := fetchImage(url)
+		if err != nil {
+			finding(SeverityError, fmt.Sprintf("Image %d does not point to a valid image: %v", i, err))
+			continue
+		}
+
+		if !img.contentTypeMatches() {
+			finding(SeverityWarning, fmt.Sprintf("Image %d is a %s image but is served as %q", i, img.Format, img.ContentType))
+		}
+
+		if img.Width > maxWidth || img.Height > maxHeight {
+			finding(SeverityWarning, fmt.Sprintf("Image %d is %dx%d, which is larger than %dx%d",
+				i, img.Width, img.Height, maxWidth, maxHeight))
+		}
+	}
+
+	return findings
+}
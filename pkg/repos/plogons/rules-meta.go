@@ -0,0 +1,53 @@
+package plogons
+
+import "github.com/google/go-cmp/cmp"
+
+func checkManifestParse(ctx *ValidationContext) []*Finding {
+	return ctx.ParseFindings
+}
+
+func checkRequiredFields(ctx *ValidationContext) []*Finding {
+	meta := ctx.Meta
+
+	missing := []struct {
+		Field string
+		Name  string
+		Unset bool
+	}{
+		{"Name", "name", meta.Name == ""},
+		{"InternalName", "internal name", meta.InternalName == ""},
+		{"Description", "description", meta.Description == ""},
+		{"AssemblyVersion", "version", meta.AssemblyVersion == ""},
+		{"DalamudApiLevel", "Dalamud API level", meta.DalamudAPILevel == 0},
+		{"RepoUrl", "repo URL", meta.RepoURL == ""},
+		{"Punchline", "punchline", meta.Punchline == ""},
+	}
+
+	findings := make([]*Finding, 0)
+	for _, m := range missing {
+		if m.Unset {
+			findings = append(findings, &Finding{
+				ID:       "meta.required",
+				Severity: SeverityError,
+				Message:  "No " + m.Name + " set",
+				Field:    m.Field,
+				File:     ctx.MetaFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkZippedMetaMatches(ctx *ValidationContext) []*Finding {
+	if cmp.Equal(*ctx.Meta, *ctx.ZippedMeta) {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "meta.zip-mismatch",
+		Severity: SeverityError,
+		Message:  "Unzipped and zipped metadata do not match",
+		File:     ctx.ZipFile,
+	}}
+}
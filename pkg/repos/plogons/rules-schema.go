@@ -0,0 +1,264 @@
+package plogons
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var internalNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// Keys that are written to manifests by the build tooling or by the plugin
+// repository itself, rather than being set by plugin authors.
+var generatedManifestKeys = []string{
+	"AcceptsFeedback",
+	"CanUnloadAsync",
+	"DownloadCount",
+	"DownloadLinkInstall",
+	"DownloadLinkTesting",
+	"DownloadLinkUpdate",
+	"FeedbackMessage",
+	"LastUpdate",
+}
+
+func checkVersions(ctx *ValidationContext) []*Finding {
+	versions := []struct {
+		Field   string
+		Version string
+	}{
+		{"AssemblyVersion", ctx.Meta.AssemblyVersion},
+		{"TestingAssemblyVersion", ctx.Meta.TestingAssemblyVersion},
+	}
+
+	findings := make([]*Finding, 0)
+	for _, v := range versions {
+		// Unset versions are reported by the required fields check
+		if v.Version == "" {
+			continue
+		}
+
+		_, err := parseVersion(v.Version)
+		if err != nil {
+			findings = append(findings, &Finding{
+				ID:       "schema.version",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("%s %q is not a valid version: %v", v.Field, v.Version, err),
+				Field:    v.Field,
+				File:     ctx.MetaFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkAPILevel(ctx *ValidationContext) []*Finding {
+	level, ok := currentAPILevel()
+	if !ok || ctx.Meta.DalamudAPILevel == 0 || ctx.Meta.DalamudAPILevel == level {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "schema.api-level",
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("Dalamud API level %d does not match the current API level %d", ctx.Meta.DalamudAPILevel, level),
+		Field:    "DalamudApiLevel",
+		File:     ctx.MetaFile,
+	}}
+}
+
+func checkInternalName(ctx *ValidationContext) []*Finding {
+	name := ctx.Meta.InternalName
+	if name == "" {
+		return nil
+	}
+
+	findings := make([]*Finding, 0)
+	if !internalNamePattern.MatchString(name) {
+		findings = append(findings, &Finding{
+			ID:       "schema.internal-name",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Internal name %q contains unsafe characters", name),
+			Field:    "InternalName",
+			File:     ctx.MetaFile,
+		})
+	}
+
+	// Plugins are laid out as <channel>/<InternalName>/<InternalName>.json,
+	// next to <channel>/<InternalName>/latest.zip
+	metaStem := strings.TrimSuffix(path.Base(ctx.MetaFile), path.Ext(ctx.MetaFile))
+	names := []struct {
+		What string
+		Name string
+		File string
+	}{
+		{"manifest file name", metaStem, ctx.MetaFile},
+		{"manifest folder name", path.Base(path.Dir(ctx.MetaFile)), ctx.MetaFile},
+		{"zip folder name", path.Base(path.Dir(ctx.ZipFile)), ctx.ZipFile},
+	}
+
+	for _, n := range names {
+		if n.Name != name {
+			findings = append(findings, &Finding{
+				ID:       "schema.internal-name",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Internal name %q does not match the %s %q", name, n.What, n.Name),
+				Field:    "InternalName",
+				File:     n.File,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkRepoURL(ctx *ValidationContext) []*Finding {
+	if ctx.Meta.RepoURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(ctx.Meta.RepoURL)
+	if err == nil && u.Scheme == "https" && u.Host != "" {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "schema.repo-url",
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("Repo URL %q is not a valid https URL", ctx.Meta.RepoURL),
+		Field:    "RepoUrl",
+		File:     ctx.MetaFile,
+	}}
+}
+
+func checkLengths(ctx *ValidationContext) []*Finding {
+	fields := []struct {
+		Field string
+		Value string
+		Max   int
+	}{
+		{"Punchline", ctx.Meta.Punchline, envInt("OPERATOR_PUNCHLINE_MAX_LENGTH", 100)},
+		{"Description", ctx.Meta.Description, envInt("OPERATOR_DESCRIPTION_MAX_LENGTH", 4000)},
+	}
+
+	findings := make([]*Finding, 0)
+	for _, f := range fields {
+		length := utf8.RuneCountInString(f.Value)
+		if length > f.Max {
+			findings = append(findings, &Finding{
+				ID:       "schema.length",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%s is %d characters long, which is over the limit of %d", f.Field, length, f.Max),
+				Field:    f.Field,
+				File:     ctx.MetaFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkUnknownKeys(ctx *ValidationContext) []*Finding {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(ctx.MetaData, &raw)
+	if err != nil {
+		return nil
+	}
+
+	// Manifest keys are matched case-insensitively, like the JSON decoder does
+	known := make(map[string]bool)
+	for _, key := range manifestKeys() {
+		known[strings.ToLower(key)] = true
+	}
+
+	unknown := make([]string, 0)
+	for key := range raw {
+		if !known[strings.ToLower(key)] {
+			unknown = append(unknown, key)
+		}
+	}
+
+	sort.Strings(unknown)
+
+	findings := make([]*Finding, len(unknown))
+	for i, key := range unknown {
+		findings[i] = &Finding{
+			ID:       "schema.unknown-keys",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Unknown manifest key %q", key),
+			Field:    key,
+			File:     ctx.MetaFile,
+		}
+	}
+
+	return findings
+}
+
+func checkCategoryTags(ctx *ValidationContext) []*Finding {
+	allowed := make(map[string]bool)
+	for _, tag := range categoryTags() {
+		allowed[tag] = true
+	}
+
+	findings := make([]*Finding, 0)
+	for _, tag := range ctx.Meta.CategoryTags {
+		if !allowed[tag] {
+			findings = append(findings, &Finding{
+				ID:       "schema.category-tags",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Category tag %q is not one of: %s", tag, strings.Join(categoryTags(), ", ")),
+				Field:    "CategoryTags",
+				File:     ctx.MetaFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+// manifestKeys returns the JSON keys of PlogonMeta, along with the keys
+// that are generated for plugin authors.
+func manifestKeys() []string {
+	keys := append([]string{}, generatedManifestKeys...)
+
+	t := reflect.TypeOf(PlogonMeta{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		keys = append(keys, name)
+	}
+
+	return keys
+}
+
+// parseVersion parses a .NET-style version number, which has between two
+// and four numeric components.
+func parseVersion(version string) ([]int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, fmt.Errorf("expected 2 to 4 components, got %d", len(parts))
+	}
+
+	components := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+			return nil, fmt.Errorf("component %q is not a non-negative integer", part)
+		}
+
+		components[i] = n
+	}
+
+	return components, nil
+}
@@ -0,0 +1,23 @@
+package plogons
+
+import "strings"
+
+func checkTestingTitle(ctx *ValidationContext) []*Finding {
+	// Check PR title if this PR is targetting testing
+	if ctx.Channel != "testing" {
+		return nil
+	}
+
+	tags := getTags(ctx.PullRequest.GetTitle())
+	for _, t := range tags {
+		if strings.ToLower(t) == "testing" {
+			return nil
+		}
+	}
+
+	return []*Finding{{
+		ID:       "title.testing-tag",
+		Severity: SeverityWarning,
+		Message:  "Testing plugin does not have tagged title",
+	}}
+}
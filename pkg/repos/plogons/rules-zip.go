@@ -0,0 +1,175 @@
+package plogons
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/karashiiro/operator/pkg/dotnet"
+)
+
+// Extensions of files that are native code on some platform, and should
+// never be needed by a plugin.
+var nativeBinaryExtensions = []string{".exe", ".so", ".dylib", ".sys", ".node"}
+
+func checkZipContents(ctx *ValidationContext) []*Finding {
+	name := ctx.Meta.InternalName
+	if name == "" {
+		return nil
+	}
+
+	findings := make([]*Finding, 0)
+	for _, expected := range []string{name + ".dll", name + ".json"} {
+		if findZipEntry(ctx.Zip, expected) == nil {
+			findings = append(findings, &Finding{
+				ID:       "zip.contents",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Zip does not contain %s", expected),
+				File:     ctx.ZipFile,
+			})
+		}
+	}
+
+	// Duplicate entries are extracted over each other, so which one ends up
+	// being used depends on the extraction order
+	seen := make(map[string]bool)
+	for _, zipFile := range ctx.Zip.File {
+		entry := strings.ToLower(path.Clean(normalizeZipPath(zipFile.Name)))
+		if seen[entry] {
+			findings = append(findings, &Finding{
+				ID:       "zip.contents",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Zip contains duplicate entry %s", zipFile.Name),
+				File:     ctx.ZipFile,
+			})
+		}
+
+		seen[entry] = true
+	}
+
+	return findings
+}
+
+func checkZipPaths(ctx *ValidationContext) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, zipFile := range ctx.Zip.File {
+		entry := normalizeZipPath(zipFile.Name)
+
+		problem := ""
+		if strings.HasPrefix(entry, "/") || (len(entry) >= 2 && entry[1] == ':') {
+			problem = "an absolute path"
+		} else {
+			for _, part := range strings.Split(entry, "/") {
+				if part == ".." {
+					problem = "a path traversal"
+					break
+				}
+			}
+		}
+
+		if problem != "" {
+			findings = append(findings, &Finding{
+				ID:       "zip.paths",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Zip entry %s is %s", zipFile.Name, problem),
+				File:     ctx.ZipFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkZipSize(ctx *ValidationContext) []*Finding {
+	findings := make([]*Finding, 0)
+
+	maxSize := int64(envInt("OPERATOR_MAX_ZIP_SIZE", 32<<20))
+	if ctx.ZipSize > maxSize {
+		findings = append(findings, &Finding{
+			ID:       "zip.size",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Zip is %d bytes, which is over the limit of %d bytes", ctx.ZipSize, maxSize),
+			File:     ctx.ZipFile,
+		})
+	}
+
+	uncompressedSize := uint64(0)
+	for _, zipFile := range ctx.Zip.File {
+		uncompressedSize += zipFile.UncompressedSize64
+	}
+
+	maxUncompressedSize := uint64(envInt("OPERATOR_MAX_ZIP_UNCOMPRESSED_SIZE", 128<<20))
+	if uncompressedSize > maxUncompressedSize {
+		findings = append(findings, &Finding{
+			ID:       "zip.size",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("Zip contents are %d bytes uncompressed, which is over the limit of %d bytes", uncompressedSize, maxUncompressedSize),
+			File:     ctx.ZipFile,
+		})
+	}
+
+	return findings
+}
+
+func checkNativeBinaries(ctx *ValidationContext) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, zipFile := range ctx.Zip.File {
+		ext := strings.ToLower(path.Ext(zipFile.Name))
+
+		native := false
+		for _, nativeExt := range nativeBinaryExtensions {
+			if ext == nativeExt {
+				native = true
+				break
+			}
+		}
+
+		// DLLs are expected, but only managed ones. Problems reading the main
+		// assembly are reported by its metadata rule.
+		if ext == ".dll" {
+			buf, err := ctx.DLL, ctx.DLLErr
+			if zipFile != ctx.DLLFile {
+				buf, err = readZipEntry(zipFile, maxDLLSize)
+			} else if err != nil {
+				continue
+			}
+
+			if err != nil {
+				findings = append(findings, &Finding{
+					ID:       "zip.native-binaries",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("Unable to inspect %s: %v", zipFile.Name, err),
+					File:     ctx.ZipFile,
+				})
+				continue
+			}
+
+			native = !dotnet.IsManaged(buf)
+		}
+
+		if native {
+			findings = append(findings, &Finding{
+				ID:       "zip.native-binaries",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("Zip contains native binary %s", zipFile.Name),
+				File:     ctx.ZipFile,
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkAssemblyInfo(ctx *ValidationContext) []*Finding {
+	if ctx.DLLFile == nil || ctx.DLLErr != nil {
+		return nil
+	}
+
+	return []*Finding{{
+		ID:       "zip.assembly-info",
+		Severity: SeverityInfo,
+		Message:  fmt.Sprintf("%s is %d bytes, SHA-256 %x", ctx.DLLFile.Name, len(ctx.DLL), sha256.Sum256(ctx.DLL)),
+		File:     ctx.ZipFile,
+	}}
+}
@@ -0,0 +1,84 @@
+package plogons
+
+import (
+	"archive/zip"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/google/go-github/v44/github"
+)
+
+// ValidationContext holds everything validation rules can inspect about a
+// pull request.
+type ValidationContext struct {
+	PullRequest *github.PullRequest
+	Channel     string
+	Files       []*gitdiff.File
+	Meta        *PlogonMeta
+	MetaData    []byte
+	MetaFile    string
+	ZippedMeta  *PlogonMeta
+	Zip         *zip.Reader
+	ZipSize     int64
+	ZipFile     string
+
+	// DLLFile is the plugin's main assembly in the zip, or nil if the zip
+	// doesn't contain it. DLL holds its contents, unless reading it failed
+	// with DLLErr.
+	DLLFile *zip.File
+	DLL     []byte
+	DLLErr  error
+
+	// BaseMeta is the manifest of the same plugin in the base branch, or nil
+	// if this is a new plugin
+	BaseMeta *PlogonMeta
+	BaseFile string
+
+	// ParseFindings are the leniencies applied while parsing the manifests
+	ParseFindings []*Finding
+}
+
+// Rule is a single validation check. Check returns any number of findings,
+// or none if the pull request passes the check.
+type Rule struct {
+	ID    string
+	Check func(ctx *ValidationContext) []*Finding
+}
+
+// rules is the registry of rules run on every pull request. Findings with
+// the same severity are reported in the order their rules appear here.
+var rules = []*Rule{
+	{ID: "manifest.parse", Check: checkManifestParse},
+	{ID: "meta.required", Check: checkRequiredFields},
+	{ID: "meta.zip-mismatch", Check: checkZippedMetaMatches},
+	{ID: "schema.version", Check: checkVersions},
+	{ID: "schema.api-level", Check: checkAPILevel},
+	{ID: "schema.internal-name", Check: checkInternalName},
+	{ID: "schema.repo-url", Check: checkRepoURL},
+	{ID: "schema.length", Check: checkLengths},
+	{ID: "schema.unknown-keys", Check: checkUnknownKeys},
+	{ID: "schema.category-tags", Check: checkCategoryTags},
+	{ID: "zip.contents", Check: checkZipContents},
+	{ID: "zip.paths", Check: checkZipPaths},
+	{ID: "zip.size", Check: checkZipSize},
+	{ID: "zip.native-binaries", Check: checkNativeBinaries},
+	{ID: "zip.assembly-info", Check: checkAssemblyInfo},
+	{ID: "assembly.metadata", Check: checkAssemblyMetadata},
+	{ID: "base.diff", Check: checkBaseDiff},
+	{ID: "base.version", Check: checkBaseVersion},
+	{ID: "base.api-level", Check: checkBaseAPILevel},
+	{ID: "base.author", Check: checkBaseAuthor},
+	{ID: "title.testing-tag", Check: checkTestingTitle},
+	{ID: "meta.icon", Check: checkIcon},
+	{ID: "meta.images", Check: checkImages},
+}
+
+func runRules(ctx *ValidationContext) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, rule := range rules {
+		findings = append(findings, rule.Check(ctx)...)
+	}
+
+	sortFindings(findings)
+
+	return findings
+}
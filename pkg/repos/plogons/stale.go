@@ -0,0 +1,75 @@
+package plogons
+
+import "time"
+
+// StaleReason describes who a stale pull request is waiting on.
+type StaleReason string
+
+const (
+	NotStale                StaleReason = ""
+	StaleWaitingOnAuthor    StaleReason = "the author"
+	StaleWaitingOnReviewers StaleReason = "reviewers"
+)
+
+func (s StaleReason) String() string {
+	return string(s)
+}
+
+// StaleRules are the number of days a pull request can go without activity
+// before it's considered stale. A rule set to 0 is disabled.
+type StaleRules struct {
+	// AuthorDays applies after changes were requested, until the author
+	// responds.
+	AuthorDays int
+	// ReviewerDays applies otherwise, counting from the last review, or from
+	// when the pull request was opened if it hasn't been reviewed yet. If the
+	// author has been active since, it counts from their last activity.
+	ReviewerDays int
+}
+
+// StaleRulesFromEnv reads the staleness rules from the environment.
+func StaleRulesFromEnv() *StaleRules {
+	return &StaleRules{
+		AuthorDays:   envInt("OPERATOR_STALE_AUTHOR_DAYS", 14),
+		ReviewerDays: envInt("OPERATOR_STALE_REVIEWER_DAYS", 7),
+	}
+}
+
+// Check returns whether a pull request is stale, who it's waiting on, and
+// how long it has gone without activity. Drafts are never stale, since
+// they aren't ready for review yet.
+func (r *StaleRules) Check(p *Plogon, now time.Time) (StaleReason, time.Duration) {
+	if p.Draft {
+		return NotStale, 0
+	}
+
+	if p.ReviewState == ReviewChangesRequested && !p.AuthorResponded {
+		idle := now.Sub(p.LastReviewed)
+		if r.AuthorDays > 0 && idle >= days(r.AuthorDays) {
+			return StaleWaitingOnAuthor, idle
+		}
+
+		return NotStale, 0
+	}
+
+	lastActivity := p.LastReviewed
+	if lastActivity.IsZero() {
+		lastActivity = p.Created
+	}
+
+	// The author pushing or commenting puts it back in the reviewers' queue
+	if p.LastAuthorActivity.After(lastActivity) {
+		lastActivity = p.LastAuthorActivity
+	}
+
+	idle := now.Sub(lastActivity)
+	if r.ReviewerDays > 0 && idle >= days(r.ReviewerDays) {
+		return StaleWaitingOnReviewers, idle
+	}
+
+	return NotStale, 0
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
@@ -1,18 +1,93 @@
 package plogons
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type PlogonLabel struct {
 	Name  string
 	Color string
 }
 
+// ReviewState is the overall review decision on a pull request.
+type ReviewState string
+
+const (
+	ReviewPending          ReviewState = "pending"
+	ReviewApproved         ReviewState = "approved"
+	ReviewChangesRequested ReviewState = "changes requested"
+)
+
+func (s ReviewState) String() string {
+	return string(s)
+}
+
 type Plogon struct {
+	Number    int
 	Title     string
 	URL       string
+	HeadSHA   string
 	Labels    []*PlogonLabel
 	Submitter string
+	Created   time.Time
 	Updated   time.Time
+	Draft     bool
+
+	// ReviewState is derived from the latest review of each reviewer.
+	// LastReviewed is zero if nobody other than the submitter has reviewed
+	// the pull request, and AuthorResponded is set if the submitter has
+	// pushed or commented since it was last reviewed, which they last did
+	// at LastAuthorActivity.
+	ReviewState        ReviewState
+	RequestedReviewers []string
+	LastReviewed       time.Time
+	AuthorResponded    bool
+	LastAuthorActivity time.Time
+
+	// Stale is set if the pull request has gone without activity for longer
+	// than the staleness rules allow, for StaleFor.
+	Stale    StaleReason
+	StaleFor time.Duration
+
+	// CI is the combined outcome of the checks on the head commit, if it has
+	// been retrieved.
+	CI *CIStatus
+}
+
+// CIState returns the combined outcome of the checks on the head commit, or
+// CINone if it isn't known.
+func (p *Plogon) CIState() CIState {
+	if p.CI == nil {
+		return CINone
+	}
+
+	return p.CI.State
+}
+
+// NeedsReviewFrom returns whether a review has been requested from the
+// provided GitHub user.
+func (p *Plogon) NeedsReviewFrom(login string) bool {
+	if login == "" {
+		return false
+	}
+
+	for _, reviewer := range p.RequestedReviewers {
+		if strings.EqualFold(reviewer, login) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolvedPlogon is a pull request that has been closed, either by merging
+// it or by closing it without merging.
+type ResolvedPlogon struct {
+	*Plogon
+	State      string
+	ResolvedBy string
+	Resolved   time.Time
 }
 
 type PlogonMeta struct {
@@ -35,23 +110,3 @@ type PlogonMeta struct {
 	DalamudAPILevel        int      `json:"DalamudApiLevel"`
 	LoadPriority           int
 }
-
-type PlogonMetaImageValidationResult struct {
-	ImageExistsOrEmpty bool
-}
-
-type PlogonMetaValidationResult struct {
-	NameSet               bool
-	InternalNameSet       bool
-	DescriptionSet        bool
-	AssemblyVersionSet    bool
-	DalamudAPILevelSet    bool
-	RepoURLSet            bool
-	PunchlineSet          bool
-	MatchesZipped         bool
-	Testing               bool
-	TestingHasTaggedTitle bool
-	IconSet               bool
-	IconExists            bool
-	Images                []*PlogonMetaImageValidationResult
-}
@@ -3,8 +3,8 @@ package plogons
 import (
 	"archive/zip"
 	"bytes"
-	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -12,219 +12,321 @@ import (
 	"strings"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
-	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v44/github"
 )
 
-func ValidatePullRequest(pr *github.PullRequest) (*PlogonMetaValidationResult, error) {
-	res := &PlogonMetaValidationResult{}
-
+// ValidatePullRequest validates every plugin touched by the pull request
+// separately. An error is only returned if the pull request couldn't be
+// inspected at all; plugins that fail to validate have their own error set.
+func ValidatePullRequest(pr *github.PullRequest) (*PullRequestValidationResult, error) {
 	files, _, err := downloadGitDiff(pr)
 	if err != nil {
 		return nil, err
 	}
 
-	uncompressedMeta, err := downloadMeta(pr, files)
+	plugins := groupPluginFiles(files)
+	if len(plugins) == 0 {
+		return nil, fmt.Errorf("could not find any plugin files in pull request")
+	}
+
+	result := &PullRequestValidationResult{
+		Plugins: make([]*PlogonMetaValidationResult, len(plugins)),
+	}
+
+	for i, plugin := range plugins {
+		var findings []*Finding
+		var err error
+		if plugin.Removed() {
+			findings = []*Finding{removalFinding(plugin)}
+		} else {
+			findings, err = validatePlugin(pr, plugin)
+		}
+
+		result.Plugins[i] = &PlogonMetaValidationResult{
+			Channel:  plugin.Channel,
+			Name:     plugin.Name,
+			Findings: findings,
+			Err:      err,
+		}
+	}
+
+	return result, nil
+}
+
+func validatePlugin(pr *github.PullRequest, plugin *pluginFiles) ([]*Finding, error) {
+	metaFile := plugin.MetaFile()
+	zipFile := plugin.ZipFile()
+
+	uncompressedMeta, metaFindings, uncompressedMetaData, err := downloadMeta(pr, metaFile)
 	if err != nil {
 		return nil, err
 	}
 
-	compressedMeta, err := downloadZippedMeta(pr, files)
+	zipReader, zipSize, err := downloadZip(pr, zipFile)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check PR title if this PR is targetting testing
-	for _, f := range files {
-		if strings.Contains(f.NewName, "testing") {
-			res.Testing = true
-			break
-		}
+	compressedMeta, zippedMetaFindings, err := readZippedMeta(zipReader, uncompressedMeta.InternalName)
+	if err != nil {
+		return nil, err
 	}
 
-	tags := getTags(pr.GetTitle())
-	for _, t := range tags {
-		if strings.ToLower(t) == "testing" {
-			res.TestingHasTaggedTitle = true
-			break
-		}
-	}
+	dllFile, dll, dllErr := readMainDLL(zipReader, uncompressedMeta.InternalName)
 
-	// Test basic fields
-	if uncompressedMeta.Name != "" {
-		res.NameSet = true
+	baseMeta, baseMetaFile, err := downloadBaseMeta(pr, metaFile, uncompressedMeta.InternalName)
+	if err != nil {
+		return nil, err
 	}
 
-	if uncompressedMeta.InternalName != "" {
-		res.InternalNameSet = true
+	ctx := &ValidationContext{
+		PullRequest: pr,
+		Channel:     plugin.Channel,
+		Files:       plugin.Files,
+		Meta:        uncompressedMeta,
+		MetaData:    uncompressedMetaData,
+		MetaFile:    metaFile,
+		ZippedMeta:  compressedMeta,
+		Zip:         zipReader,
+		ZipSize:     zipSize,
+		ZipFile:     zipFile,
+		DLLFile:     dllFile,
+		DLL:         dll,
+		DLLErr:      dllErr,
+		BaseMeta:    baseMeta,
+		BaseFile:    baseMetaFile,
+
+		ParseFindings: append(metaFindings, zippedMetaFindings...),
 	}
 
-	if uncompressedMeta.Description != "" {
-		res.DescriptionSet = true
+	return runRules(ctx), nil
+}
+
+// removalFinding reports that a pull request removes a plugin from one of
+// the channels.
+func removalFinding(plugin *pluginFiles) *Finding {
+	return &Finding{
+		ID:       "plugin.removal",
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("Plugin is removed from %s", plugin.Channel),
+		File:     path.Join(plugin.Dir(), plugin.Name+".json"),
 	}
+}
 
-	if uncompressedMeta.AssemblyVersion != "" {
-		res.AssemblyVersionSet = true
+func downloadMeta(pr *github.PullRequest, metaFilePath string) (*PlogonMeta, []*Finding, []byte, error) {
+	metaFileURL, err := getHeadBranchFileURL(metaFilePath, pr)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	if uncompressedMeta.DalamudAPILevel != 0 {
-		res.DalamudAPILevelSet = true
+	metaFile, err := http.Get(metaFileURL)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	defer metaFile.Body.Close()
 
-	if uncompressedMeta.RepoURL != "" {
-		res.RepoURLSet = true
+	if metaFile.StatusCode != http.StatusOK {
+		return nil, nil, nil, fmt.Errorf("unexpected status code %d for %s", metaFile.StatusCode, metaFilePath)
 	}
 
-	if uncompressedMeta.Punchline != "" {
-		res.PunchlineSet = true
+	metaFileBuf, err := ioutil.ReadAll(metaFile.Body)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Check that the icon exists if specified
-	if uncompressedMeta.IconURL != "" {
-		res.IconSet = true
+	return parseManifest(metaFileBuf, metaFilePath)
+}
 
-		exists := true
-		r, err := http.Head(uncompressedMeta.IconURL)
+// downloadBaseMeta downloads the manifest for the provided plugin from the
+// pull request's base branch. Plugins that are being moved between stable
+// and testing are looked up in the other channel as well. If the plugin
+// isn't in the base branch at all, nil is returned.
+func downloadBaseMeta(pr *github.PullRequest, metaFilePath, internalName string) (*PlogonMeta, string, error) {
+	// The internal name comes from the pull request, so it can't be used in
+	// a URL until it's known to be a single safe path segment. Names that
+	// aren't are reported by the schema rules instead.
+	if !internalNamePattern.MatchString(internalName) {
+		return nil, "", nil
+	}
+
+	channel := strings.Split(metaFilePath, "/")[0]
+	channels := []string{channel}
+	if channel == "testing" {
+		channels = append(channels, "plugins")
+	} else if channel == "plugins" {
+		channels = append(channels, "testing")
+	}
+
+	for _, c := range channels {
+		baseFilePath := path.Join(c, internalName, internalName+".json")
+		baseFileURL, err := getBaseBranchFileURL(baseFilePath, pr)
 		if err != nil {
-			exists = false
-		} else {
-			exists = r.StatusCode == 200
+			return nil, "", err
 		}
 
-		res.IconExists = exists
-	}
+		baseFile, err := http.Get(baseFileURL)
+		if err != nil {
+			return nil, "", err
+		}
 
-	// Check that the images exist if specified
-	for _, url := range uncompressedMeta.ImageURLs {
-		existsOrEmpty := true
-		if url != "" {
-			r, err := http.Head(url)
-			if err != nil {
-				existsOrEmpty = false
-			} else {
-				existsOrEmpty = r.StatusCode == 200
-			}
+		baseFileBuf, err := ioutil.ReadAll(baseFile.Body)
+		baseFile.Body.Close()
+		if err != nil {
+			return nil, "", err
 		}
 
-		res.Images = append(res.Images, &PlogonMetaImageValidationResult{
-			ImageExistsOrEmpty: existsOrEmpty,
-		})
-	}
+		if baseFile.StatusCode == http.StatusNotFound {
+			continue
+		} else if baseFile.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("unexpected status code %d for %s", baseFile.StatusCode, baseFileURL)
+		}
 
-	// Check that the two metadata files are equivalent
-	if cmp.Equal(*uncompressedMeta, *compressedMeta) {
-		res.MatchesZipped = true
+		meta, _, _, err := parseManifest(baseFileBuf, baseFilePath)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return meta, baseFilePath, nil
 	}
 
-	return res, nil
+	return nil, "", nil
 }
 
-func downloadMeta(pr *github.PullRequest, diffFiles []*gitdiff.File) (*PlogonMeta, error) {
-	meta := &PlogonMeta{}
+// maxZipDownloadSize is the largest plugin zip that will be downloaded for
+// inspection. Archives over the configured size limit are still inspected
+// as long as they fit under this.
+const maxZipDownloadSize = 256 << 20
 
-	metaFileInfo := findMetaFile(diffFiles)
-	if metaFileInfo == nil {
-		return nil, fmt.Errorf("could not find metadata file in pull request")
+func downloadZip(pr *github.PullRequest, zipFilePath string) (*zip.Reader, int64, error) {
+	zipFileURL, err := getHeadBranchFileURL(zipFilePath, pr)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	metaFileURL, err := getHeadBranchFileURL(metaFileInfo, pr)
+	zipFile, err := http.Get(zipFileURL)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	defer zipFile.Body.Close()
 
-	metaFile, err := http.Get(metaFileURL)
-	if err != nil {
-		return nil, err
+	if zipFile.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected status code %d for %s", zipFile.StatusCode, zipFilePath)
 	}
-	defer metaFile.Body.Close()
 
-	metaFileBuf, err := ioutil.ReadAll(metaFile.Body)
+	zipFileBuf, err := ioutil.ReadAll(io.LimitReader(zipFile.Body, maxZipDownloadSize+1))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	metaFileData := strings.TrimLeftFunc(string(metaFileBuf), func(r rune) bool {
-		return r != '{'
-	})
-	metaFileData = strings.TrimRightFunc(metaFileData, func(r rune) bool {
-		return r != '}'
-	})
+	if len(zipFileBuf) > maxZipDownloadSize {
+		return nil, 0, fmt.Errorf("zip file is larger than %d bytes", maxZipDownloadSize)
+	}
 
-	err = json.Unmarshal([]byte(metaFileData), meta)
+	zipReader, err := zip.NewReader(bytes.NewReader(zipFileBuf), int64(len(zipFileBuf)))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return meta, nil
+	return zipReader, int64(len(zipFileBuf)), nil
 }
 
-func downloadZippedMeta(pr *github.PullRequest, diffFiles []*gitdiff.File) (*PlogonMeta, error) {
-	meta := &PlogonMeta{}
+// readZippedMeta reads the manifest embedded in a plugin zip. The manifest
+// named after the plugin's internal name is preferred, falling back to the
+// only manifest at the root of the archive.
+func readZippedMeta(zipReader *zip.Reader, internalName string) (*PlogonMeta, []*Finding, error) {
+	metaFile := findZipEntry(zipReader, internalName+".json")
+	if metaFile == nil {
+		candidates := make([]*zip.File, 0)
+		for _, zipFile := range zipReader.File {
+			name := normalizeZipPath(zipFile.Name)
+			if !strings.Contains(name, "/") && strings.HasSuffix(name, ".json") {
+				candidates = append(candidates, zipFile)
+			}
+		}
 
-	zipFileInfo := findZipFile(diffFiles)
-	if zipFileInfo == nil {
-		return nil, fmt.Errorf("could not find zip file in pull request")
+		if len(candidates) > 1 {
+			return nil, nil, fmt.Errorf("could not find %s.json in zip, and it contains %d other manifests", internalName, len(candidates))
+		} else if len(candidates) == 1 {
+			metaFile = candidates[0]
+		}
 	}
 
-	zipFileURL, err := getHeadBranchFileURL(zipFileInfo, pr)
-	if err != nil {
-		return nil, err
+	if metaFile == nil {
+		return nil, nil, fmt.Errorf("could not find metadata file in zip")
 	}
 
-	zipFile, err := http.Get(zipFileURL)
+	metaFileBuf, err := readZipEntry(metaFile, maxZipDownloadSize)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer zipFile.Body.Close()
 
-	zipFileBuf, err := ioutil.ReadAll(zipFile.Body)
+	meta, findings, _, err := parseManifest(metaFileBuf, metaFile.Name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	zipReader, err := zip.NewReader(bytes.NewReader(zipFileBuf), zipFile.ContentLength)
-	if err != nil {
-		return nil, err
+	return meta, findings, nil
+}
+
+// maxDLLSize is the largest DLL that will be decompressed for inspection.
+const maxDLLSize = 32 << 20
+
+// readMainDLL finds and reads the plugin's main assembly, which is named
+// after its internal name. Nothing is returned if the zip doesn't contain
+// it.
+func readMainDLL(zipReader *zip.Reader, internalName string) (*zip.File, []byte, error) {
+	if internalName == "" {
+		return nil, nil, nil
 	}
 
-	var metaFile *zip.File
+	dllFile := findZipEntry(zipReader, internalName+".dll")
+	if dllFile == nil {
+		return nil, nil, nil
+	}
+
+	dll, err := readZipEntry(dllFile, maxDLLSize)
+	return dllFile, dll, err
+}
+
+// findZipEntry finds the entry at the root of the zip with the provided
+// name. Entries in subdirectories aren't loaded by Dalamud, so they are
+// ignored.
+func findZipEntry(zipReader *zip.Reader, name string) *zip.File {
 	for _, zipFile := range zipReader.File {
-		if strings.HasSuffix(zipFile.Name, ".json") {
-			metaFile = zipFile
+		if normalizeZipPath(zipFile.Name) == name {
+			return zipFile
 		}
 	}
 
-	if metaFile == nil {
-		return nil, fmt.Errorf("could not find metadata file in zip")
-	}
+	return nil
+}
 
-	metaFileContents, err := metaFile.Open()
+// readZipEntry reads the contents of a zip entry, failing if it decompresses
+// to more than limit bytes.
+func readZipEntry(zipFile *zip.File, limit int64) ([]byte, error) {
+	contents, err := zipFile.Open()
 	if err != nil {
 		return nil, err
 	}
-	defer metaFileContents.Close()
+	defer contents.Close()
 
-	metaFileBuf, err := ioutil.ReadAll(metaFileContents)
+	buf, err := ioutil.ReadAll(io.LimitReader(contents, limit+1))
 	if err != nil {
 		return nil, err
 	}
 
-	metaFileData := strings.TrimLeftFunc(string(metaFileBuf), func(r rune) bool {
-		return r != '{'
-	})
-	metaFileData = strings.TrimRightFunc(metaFileData, func(r rune) bool {
-		return r != '}'
-	})
-
-	err = json.Unmarshal([]byte(metaFileData), meta)
-	if err != nil {
-		return nil, err
+	if int64(len(buf)) > limit {
+		return nil, fmt.Errorf("zip entry %s is larger than %d bytes", zipFile.Name, limit)
 	}
 
-	return meta, nil
+	return buf, nil
+}
+
+func normalizeZipPath(name string) string {
+	return strings.ReplaceAll(name, "\\", "/")
 }
 
-func getHeadBranchFileURL(file *gitdiff.File, pr *github.PullRequest) (string, error) {
+func getHeadBranchFileURL(filePath string, pr *github.PullRequest) (string, error) {
 	if pr.Head == nil {
 		return "", fmt.Errorf("pull request has nil head branch")
 	}
@@ -239,7 +341,27 @@ func getHeadBranchFileURL(file *gitdiff.File, pr *github.PullRequest) (string, e
 	}
 
 	fileURL.Path = path.Join(fileURL.Path, pr.Head.Repo.GetFullName(),
-		pr.Head.GetRef(), strings.TrimLeft(file.NewName, "b/"))
+		pr.Head.GetRef(), filePath)
+
+	return fileURL.String(), nil
+}
+
+func getBaseBranchFileURL(filePath string, pr *github.PullRequest) (string, error) {
+	if pr.Base == nil {
+		return "", fmt.Errorf("pull request has nil base branch")
+	}
+
+	if pr.Base.Repo == nil {
+		return "", fmt.Errorf("pull request base branch has nil repo")
+	}
+
+	fileURL, err := url.Parse("https://raw.githubusercontent.com")
+	if err != nil {
+		return "", err
+	}
+
+	fileURL.Path = path.Join(fileURL.Path, pr.Base.Repo.GetFullName(),
+		pr.Base.GetRef(), filePath)
 
 	return fileURL.String(), nil
 }
@@ -271,30 +393,6 @@ func getTags(title string) []string {
 	return tags
 }
 
-func findZipFile(diffFiles []*gitdiff.File) *gitdiff.File {
-	var fileInfo *gitdiff.File
-	for _, f := range diffFiles {
-		if strings.HasSuffix(f.NewName, ".zip") {
-			fileInfo = f
-			break
-		}
-	}
-
-	return fileInfo
-}
-
-func findMetaFile(diffFiles []*gitdiff.File) *gitdiff.File {
-	var fileInfo *gitdiff.File
-	for _, f := range diffFiles {
-		if strings.HasSuffix(f.NewName, ".json") {
-			fileInfo = f
-			break
-		}
-	}
-
-	return fileInfo
-}
-
 func downloadGitDiff(pr *github.PullRequest) ([]*gitdiff.File, string, error) {
 	diffURL := pr.GetDiffURL()
 	diff, err := http.Get(diffURL)
@@ -0,0 +1 @@
+ALTER TABLE Report ADD IF NOT EXISTS message_id VARCHAR(255) UNIQUE;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ReportSnapshot (
+    reader_id INTEGER     NOT NULL,
+    pr_number INTEGER     NOT NULL,
+    title     TEXT        NOT NULL,
+    url       TEXT        NOT NULL,
+    head_sha  VARCHAR(40) NOT NULL,
+    labels    TEXT[]      NOT NULL,
+    problems  TEXT[]      NOT NULL,
+
+    PRIMARY KEY (reader_id, pr_number),
+    FOREIGN KEY (reader_id) REFERENCES Reader(id) DEFERRABLE INITIALLY DEFERRED
+);
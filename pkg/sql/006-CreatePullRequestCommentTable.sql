@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS PullRequestComment (
+    pr_number     INTEGER     NOT NULL,
+    comment_id    BIGINT      NOT NULL,
+    head_sha      VARCHAR(40) NOT NULL,
+    findings_hash VARCHAR(64) NOT NULL,
+    updated_time  TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (pr_number)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS LabelChange (
+    id            SERIAL,
+    pr_number     INTEGER      NOT NULL,
+    head_sha      VARCHAR(40)  NOT NULL,
+    label         VARCHAR(255) NOT NULL,
+    added         BOOLEAN      NOT NULL,
+    reason        TEXT         NOT NULL,
+    findings_hash VARCHAR(64)  NOT NULL,
+    changed_time  TIMESTAMPTZ  NOT NULL,
+
+    PRIMARY KEY (id)
+);
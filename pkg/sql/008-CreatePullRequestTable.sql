@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS PullRequest (
+    number           INTEGER     NOT NULL,
+    state            VARCHAR(16) NOT NULL,
+    head_sha         VARCHAR(40) NOT NULL,
+    updated_time     TIMESTAMPTZ NOT NULL,
+    data             JSONB       NOT NULL,
+    validation_stale BOOLEAN     NOT NULL,
+
+    PRIMARY KEY (number)
+);
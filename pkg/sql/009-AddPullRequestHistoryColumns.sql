@@ -0,0 +1,31 @@
+BEGIN;
+
+ALTER TABLE PullRequest ADD IF NOT EXISTS title           TEXT         NOT NULL DEFAULT '';
+ALTER TABLE PullRequest ADD IF NOT EXISTS submitter       VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE PullRequest ADD IF NOT EXISTS url             TEXT         NOT NULL DEFAULT '';
+ALTER TABLE PullRequest ADD IF NOT EXISTS created_time    TIMESTAMPTZ;
+ALTER TABLE PullRequest ADD IF NOT EXISTS first_seen_time TIMESTAMPTZ  NOT NULL DEFAULT now();
+ALTER TABLE PullRequest ADD IF NOT EXISTS closed_time     TIMESTAMPTZ;
+ALTER TABLE PullRequest ADD IF NOT EXISTS merged          BOOLEAN      NOT NULL DEFAULT FALSE;
+ALTER TABLE PullRequest ADD IF NOT EXISTS resolved_by     VARCHAR(255);
+
+-- Fill in the new columns for pull requests stored from webhooks. Every
+-- stored pull request gets a creation time, so rows that already have one
+-- were filled in before or stored since, and are left alone.
+UPDATE PullRequest
+SET
+    title = COALESCE(data->>'title', title),
+    submitter = COALESCE(data->'user'->>'login', submitter),
+    url = COALESCE(data->>'html_url', url),
+    created_time = COALESCE((data->>'created_at')::TIMESTAMPTZ, first_seen_time),
+    closed_time = COALESCE((data->>'closed_at')::TIMESTAMPTZ, closed_time),
+    merged = merged OR data->>'merged_at' IS NOT NULL
+WHERE created_time IS NULL;
+
+ALTER TABLE PullRequest ALTER title DROP DEFAULT;
+ALTER TABLE PullRequest ALTER submitter DROP DEFAULT;
+ALTER TABLE PullRequest ALTER url DROP DEFAULT;
+ALTER TABLE PullRequest ALTER first_seen_time DROP DEFAULT;
+ALTER TABLE PullRequest ALTER merged DROP DEFAULT;
+
+COMMIT;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS PullRequestLabel (
+    pr_number INTEGER      NOT NULL,
+    name      VARCHAR(255) NOT NULL,
+    color     VARCHAR(6)   NOT NULL,
+
+    PRIMARY KEY (pr_number, name),
+    FOREIGN KEY (pr_number) REFERENCES PullRequest(number) ON DELETE CASCADE
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS ValidationRun (
+    id             SERIAL,
+    pr_number      INTEGER     NOT NULL,
+    head_sha       VARCHAR(40) NOT NULL,
+    validated_time TIMESTAMPTZ NOT NULL,
+    error          TEXT,
+    plugins        TEXT[]      NOT NULL,
+    plugin_errors  TEXT[]      NOT NULL,
+    passed         BOOLEAN     NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (pr_number) REFERENCES PullRequest(number) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ValidationRun_pr_number_idx ON ValidationRun (pr_number, validated_time);
+
+CREATE TABLE IF NOT EXISTS ValidationFinding (
+    run_id   INTEGER      NOT NULL,
+    position INTEGER      NOT NULL,
+    plugin   TEXT         NOT NULL,
+    rule_id  VARCHAR(255) NOT NULL,
+    severity SMALLINT     NOT NULL,
+    message  TEXT         NOT NULL,
+    field    VARCHAR(255) NOT NULL,
+    file     TEXT         NOT NULL,
+
+    PRIMARY KEY (run_id, position),
+    FOREIGN KEY (run_id) REFERENCES ValidationRun(id) ON DELETE CASCADE
+);
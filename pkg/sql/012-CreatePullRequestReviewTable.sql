@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS PullRequestReview (
+    id             BIGINT       NOT NULL,
+    pr_number      INTEGER      NOT NULL,
+    reviewer       VARCHAR(255) NOT NULL,
+    state          VARCHAR(32)  NOT NULL,
+    submitted_time TIMESTAMPTZ  NOT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (pr_number) REFERENCES PullRequest(number) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS PullRequestReview_pr_number_idx ON PullRequestReview (pr_number, submitted_time);
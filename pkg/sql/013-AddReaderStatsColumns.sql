@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE Reader ADD IF NOT EXISTS weekly_stats    BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Reader ADD IF NOT EXISTS stats_sent_time TIMESTAMPTZ;
+
+COMMIT;
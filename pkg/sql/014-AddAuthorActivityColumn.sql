@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE PullRequest ADD IF NOT EXISTS author_activity_time TIMESTAMPTZ;
+
+UPDATE PullRequest SET author_activity_time = COALESCE(created_time, first_seen_time)
+WHERE author_activity_time IS NULL;
+
+COMMIT;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS CIStatus (
+    head_sha     VARCHAR(40) NOT NULL,
+    state        VARCHAR(16) NOT NULL,
+    checks       JSONB       NOT NULL,
+    updated_time TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (head_sha)
+);
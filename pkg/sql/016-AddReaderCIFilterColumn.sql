@@ -0,0 +1 @@
+ALTER TABLE Reader ADD IF NOT EXISTS green_ci_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE Reader ADD IF NOT EXISTS stale_interval  INTERVAL;
+ALTER TABLE Reader ADD IF NOT EXISTS stale_sent_time TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE Reader ADD IF NOT EXISTS report_sort  VARCHAR(16);
+ALTER TABLE Reader ADD IF NOT EXISTS report_group VARCHAR(16);
+
+COMMIT;
@@ -0,0 +1 @@
+ALTER TABLE PullRequest ADD IF NOT EXISTS reviews_synced BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/html"
+	"github.com/karashiiro/operator/pkg/outlook"
+	"github.com/karashiiro/operator/pkg/unsubscribe"
+)
+
+// Period is the length of time covered by each statistics email.
+const Period = 7 * 24 * time.Hour
+
+// StatsJob emails the review queue statistics for the past week to every
+// reader who has opted into them.
+type StatsJob struct {
+	Pool *pgx.ConnPool
+}
+
+func (j *StatsJob) Execute() {
+	conn, err := j.Pool.Acquire()
+	if err != nil {
+		log.Printf("Failed to acquire database connection: %v\n", err)
+		return
+	}
+	defer j.Pool.Release(conn)
+
+	readers, err := getReadersToNotify(conn)
+	if err != nil {
+		log.Printf("Unable to retrieve readers: %v\n", err)
+		return
+	}
+
+	if len(readers) == 0 {
+		return
+	}
+
+	log.Println("Sending weekly statistics")
+
+	until := time.Now()
+	s, err := Compute(conn, until.Add(-Period), until)
+	if err != nil {
+		log.Printf("Failed to compute statistics: %v\n", err)
+		return
+	}
+
+	message, err := BuildTemplate(s)
+	if err != nil {
+		log.Printf("Failed to build template: %v\n", err)
+		return
+	}
+
+	for _, r := range readers {
+		log.Printf("Sending statistics email to %s\n", r.email)
+		err := outlook.Send(&outlook.Message{
+			To:      r.email,
+			Subject: "Weekly Dalamud Plugin Pull Request Statistics",
+			HTML:    message.HTML,
+			Text:    message.Text,
+			Headers: unsubscribe.Headers(r.id),
+		})
+		if err != nil {
+			log.Printf("Unable to send mail: %v\n", err)
+			continue
+		}
+
+		err = storeStatsSent(conn, r.id, until)
+		if err != nil {
+			log.Printf("Unable to store statistics log: %v\n", err)
+			continue
+		}
+	}
+}
+
+func (j *StatsJob) Description() string {
+	return "StatsJob"
+}
+
+func (j *StatsJob) Key() int {
+	h := fnv.New32a()
+	_, err := h.Write([]byte(j.Description()))
+	if err != nil {
+		log.Println(err)
+		return -1
+	}
+
+	return int(h.Sum32())
+}
+
+func BuildTemplate(s *Stats) (*html.Body, error) {
+	return html.Render(s, "stats")
+}
+
+type reader struct {
+	id    int
+	email string
+}
+
+// getReadersToNotify returns the readers who opted into weekly statistics
+// and haven't received them in the past week.
+func getReadersToNotify(conn *pgx.Conn) ([]*reader, error) {
+	rows, err := conn.Query(`
+		SELECT id, email
+		FROM Reader
+		WHERE active AND weekly_stats
+			AND (stats_sent_time IS NULL OR stats_sent_time + $1::INTERVAL <= now());
+	`, Period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readers := make([]*reader, 0)
+	for rows.Next() {
+		r := &reader{}
+		err := rows.Scan(&r.id, &r.email)
+		if err != nil {
+			return nil, err
+		}
+
+		readers = append(readers, r)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return readers, nil
+}
+
+func storeStatsSent(conn *pgx.Conn, readerId int, sent time.Time) error {
+	_, err := conn.Exec("UPDATE Reader SET stats_sent_time = $2 WHERE id = $1;", readerId, sent)
+	return err
+}
@@ -0,0 +1,267 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// topFindingsLimit is the number of findings included in the most common
+// findings.
+const topFindingsLimit = 10
+
+// Stats are the review queue metrics for a period of time, computed from the
+// stored pull request history.
+type Stats struct {
+	Since time.Time
+	Until time.Time
+
+	// Opened, Merged and Closed count the pull requests opened, merged and
+	// closed without being merged during the period. Open is the size of the
+	// queue at the end of it.
+	Opened int
+	Merged int
+	Closed int
+	Open   int
+
+	// FirstReview is the time from opening to the first review by someone
+	// other than the submitter, for pull requests first reviewed during the
+	// period.
+	FirstReview Turnaround
+	// Merge is the time from opening to merging, for pull requests merged
+	// during the period.
+	Merge Turnaround
+	// Iterations is the number of commits validated before validation first
+	// passed, including the passing one, for pull requests that first passed
+	// during the period.
+	Iterations Iterations
+
+	Queue       []*QueueSize
+	TopFindings []*FindingCount
+}
+
+// Turnaround summarizes how long something took across several pull requests.
+type Turnaround struct {
+	Count  int
+	Median time.Duration
+	Mean   time.Duration
+}
+
+// Iterations summarizes a number of attempts across several pull requests.
+type Iterations struct {
+	Count  int
+	Median float64
+	Mean   float64
+}
+
+// QueueSize is the number of pull requests open at a point in time.
+type QueueSize struct {
+	Time time.Time
+	Open int
+}
+
+// FindingCount is the number of pull requests a validation rule reported
+// findings on.
+type FindingCount struct {
+	Rule         string
+	PullRequests int
+}
+
+// Compute calculates the metrics for the period between since and until.
+func Compute(conn *pgx.Conn, since, until time.Time) (*Stats, error) {
+	s := &Stats{
+		Since: since,
+		Until: until,
+	}
+
+	err := conn.QueryRow(`
+		SELECT
+			count(*) FILTER (WHERE COALESCE(created_time, first_seen_time) >= $1
+				AND COALESCE(created_time, first_seen_time) < $2),
+			count(*) FILTER (WHERE merged AND closed_time >= $1 AND closed_time < $2),
+			count(*) FILTER (WHERE NOT merged AND closed_time >= $1 AND closed_time < $2),
+			count(*) FILTER (WHERE COALESCE(created_time, first_seen_time) < $2
+				AND (closed_time IS NULL OR closed_time >= $2))
+		FROM PullRequest;
+	`, since, until).Scan(&s.Opened, &s.Merged, &s.Closed, &s.Open)
+	if err != nil {
+		return nil, err
+	}
+
+	s.FirstReview, err = queryTurnaround(conn, `
+		SELECT extract(epoch FROM min(PullRequestReview.submitted_time)
+			- COALESCE(PullRequest.created_time, PullRequest.first_seen_time))::DOUBLE PRECISION
+		FROM PullRequest
+		JOIN PullRequestReview
+			ON PullRequestReview.pr_number = PullRequest.number
+			AND PullRequestReview.reviewer <> PullRequest.submitter
+		GROUP BY PullRequest.number
+		HAVING min(PullRequestReview.submitted_time) >= $1
+			AND min(PullRequestReview.submitted_time) < $2
+	`, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Merge, err = queryTurnaround(conn, `
+		SELECT extract(epoch FROM closed_time - COALESCE(created_time, first_seen_time))::DOUBLE PRECISION
+		FROM PullRequest
+		WHERE merged AND closed_time >= $1 AND closed_time < $2
+	`, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Iterations, err = queryIterations(conn, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Queue, err = queryQueue(conn, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	s.TopFindings, err = queryTopFindings(conn, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// queryTurnaround summarizes the durations, in seconds, returned by a query.
+func queryTurnaround(conn *pgx.Conn, query string, since, until time.Time) (Turnaround, error) {
+	var t Turnaround
+	var median, mean *float64
+	err := conn.QueryRow(`
+		SELECT count(*), percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds), avg(seconds)
+		FROM (`+query+`) AS Durations (seconds);
+	`, since, until).Scan(&t.Count, &median, &mean)
+	if err != nil {
+		return Turnaround{}, err
+	}
+
+	if median != nil {
+		t.Median = time.Duration(*median * float64(time.Second))
+	}
+
+	if mean != nil {
+		t.Mean = time.Duration(*mean * float64(time.Second))
+	}
+
+	return t, nil
+}
+
+// queryIterations counts the distinct head commits validated up to and
+// including the first passing validation of each pull request.
+func queryIterations(conn *pgx.Conn, since, until time.Time) (Iterations, error) {
+	var it Iterations
+	var median, mean *float64
+	err := conn.QueryRow(`
+		SELECT count(*), percentile_cont(0.5) WITHIN GROUP (ORDER BY iterations), avg(iterations)
+		FROM (
+			SELECT (
+				SELECT count(DISTINCT ValidationRun.head_sha)
+				FROM ValidationRun
+				WHERE ValidationRun.pr_number = FirstPass.pr_number
+					AND ValidationRun.validated_time <= FirstPass.validated_time
+			)::DOUBLE PRECISION AS iterations
+			FROM (
+				SELECT pr_number, min(validated_time) AS validated_time
+				FROM ValidationRun
+				WHERE passed
+				GROUP BY pr_number
+			) AS FirstPass
+			WHERE FirstPass.validated_time >= $1 AND FirstPass.validated_time < $2
+		) AS Iterations;
+	`, since, until).Scan(&it.Count, &median, &mean)
+	if err != nil {
+		return Iterations{}, err
+	}
+
+	if median != nil {
+		it.Median = *median
+	}
+
+	if mean != nil {
+		it.Mean = *mean
+	}
+
+	return it, nil
+}
+
+// queryQueue returns the number of open pull requests at the end of each day
+// in the period.
+func queryQueue(conn *pgx.Conn, since, until time.Time) ([]*QueueSize, error) {
+	rows, err := conn.Query(`
+		SELECT day, (
+			SELECT count(*)
+			FROM PullRequest
+			WHERE COALESCE(created_time, first_seen_time) <= day
+				AND (closed_time IS NULL OR closed_time > day)
+		)
+		FROM generate_series($1::TIMESTAMPTZ + INTERVAL '1 day', $2::TIMESTAMPTZ, INTERVAL '1 day') AS day
+		ORDER BY day;
+	`, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := make([]*QueueSize, 0)
+	for rows.Next() {
+		q := &QueueSize{}
+		err := rows.Scan(&q.Time, &q.Open)
+		if err != nil {
+			return nil, err
+		}
+
+		queue = append(queue, q)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return queue, nil
+}
+
+// queryTopFindings returns the validation rules that reported errors or
+// warnings on the most pull requests during the period. Info findings are
+// reported on nearly every pull request, so they're left out.
+func queryTopFindings(conn *pgx.Conn, since, until time.Time) ([]*FindingCount, error) {
+	rows, err := conn.Query(`
+		SELECT ValidationFinding.rule_id, count(DISTINCT ValidationRun.pr_number) AS pull_requests
+		FROM ValidationFinding
+		JOIN ValidationRun
+			ON ValidationRun.id = ValidationFinding.run_id
+		WHERE ValidationRun.validated_time >= $1 AND ValidationRun.validated_time < $2
+			AND ValidationFinding.severity <> $4
+		GROUP BY ValidationFinding.rule_id
+		ORDER BY pull_requests DESC, ValidationFinding.rule_id
+		LIMIT $3;
+	`, since, until, topFindingsLimit, int16(plogons.SeverityInfo))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	findings := make([]*FindingCount, 0)
+	for rows.Next() {
+		f := &FindingCount{}
+		err := rows.Scan(&f.Rule, &f.PullRequests)
+		if err != nil {
+			return nil, err
+		}
+
+		findings = append(findings, f)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,80 @@
+package threading
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var messageIdPattern = regexp.MustCompile(`^<operator\.report\.(?P<reader>\d+)\.(?P<report>\d+)@(?P<domain>[^>]+)>$`)
+var angleAddrPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
+// ReportRef identifies a report that was sent to a reader.
+type ReportRef struct {
+	ReaderId int
+	ReportId int
+}
+
+// MessageID builds the stable Message-ID of a report. The reader and report
+// IDs are encoded into it, so replies can be matched back to the report.
+func MessageID(readerId, reportId int) string {
+	return fmt.Sprintf("<operator.report.%d.%d@%s>", readerId, reportId, domain())
+}
+
+// ParseMessageID extracts the report reference from a Message-ID generated
+// by MessageID. Message-IDs from other domains are ignored.
+func ParseMessageID(messageId string) (*ReportRef, bool) {
+	matches := messageIdPattern.FindStringSubmatch(strings.TrimSpace(messageId))
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	if !strings.EqualFold(matches[messageIdPattern.SubexpIndex("domain")], domain()) {
+		return nil, false
+	}
+
+	readerId, err := strconv.Atoi(matches[messageIdPattern.SubexpIndex("reader")])
+	if err != nil {
+		return nil, false
+	}
+
+	reportId, err := strconv.Atoi(matches[messageIdPattern.SubexpIndex("report")])
+	if err != nil {
+		return nil, false
+	}
+
+	return &ReportRef{
+		ReaderId: readerId,
+		ReportId: reportId,
+	}, true
+}
+
+// FindReport returns the most recent report referenced by the provided
+// In-Reply-To and References header values.
+func FindReport(inReplyTo, references string) (*ReportRef, bool) {
+	if ref, ok := ParseMessageID(inReplyTo); ok {
+		return ref, true
+	}
+
+	// References are ordered from the oldest to the newest message
+	ids := angleAddrPattern.FindAllString(references, -1)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ref, ok := ParseMessageID(ids[i]); ok {
+			return ref, true
+		}
+	}
+
+	return nil, false
+}
+
+func domain() string {
+	addr := os.Getenv("OPERATOR_EMAIL")
+	at := strings.LastIndex(addr, "@")
+	if at == -1 || at == len(addr)-1 {
+		return "localhost"
+	}
+
+	return addr[at+1:]
+}
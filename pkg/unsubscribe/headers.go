@@ -0,0 +1,79 @@
+package unsubscribe
+
+import (
+	"fmt"
+	"log"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SubjectPrefix is the subject line prefix of unsubscribe emails. A token
+// may follow it, separated by a space.
+const SubjectPrefix = "[op] unsubscribe"
+
+// Headers builds the RFC 2369 and RFC 8058 list headers for an email sent
+// to the provided reader. If no unsubscribe secret is configured, the mailto:
+// link falls back to a plain unsubscribe subject line.
+func Headers(readerId int) textproto.MIMEHeader {
+	headers := textproto.MIMEHeader{}
+
+	token := ""
+	if os.Getenv("OPERATOR_UNSUBSCRIBE_SECRET") != "" {
+		signed, err := Sign(readerId)
+		if err != nil {
+			log.Printf("Unable to sign unsubscribe token: %v\n", err)
+		}
+
+		token = signed
+	}
+
+	links := make([]string, 0, 2)
+
+	// The one-click URL is only usable when a token is available and the
+	// unsubscribe endpoint is reachable over HTTPS
+	publicURL := os.Getenv("OPERATOR_PUBLIC_URL")
+	if token != "" && strings.HasPrefix(publicURL, "https://") {
+		links = append(links, fmt.Sprintf("<%s>", URL(publicURL, token)))
+		headers.Set("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+
+	subject := SubjectPrefix
+	if token != "" {
+		subject += " " + token
+	}
+
+	mailto := url.URL{
+		Scheme:   "mailto",
+		Opaque:   os.Getenv("OPERATOR_EMAIL"),
+		RawQuery: "subject=" + url.PathEscape(subject),
+	}
+	links = append(links, fmt.Sprintf("<%s>", mailto.String()))
+
+	headers.Set("List-Unsubscribe", strings.Join(links, ", "))
+
+	return headers
+}
+
+// URL returns the one-click unsubscribe URL for a token.
+func URL(publicURL, token string) string {
+	return strings.TrimRight(publicURL, "/") + "/unsubscribe?" + url.Values{"token": {token}}.Encode()
+}
+
+// tokenPattern matches the format of the tokens created by Sign, which is a
+// reader ID and an unpadded base64url-encoded SHA-256 MAC.
+var tokenPattern = regexp.MustCompile(`^[0-9]+\.[A-Za-z0-9_-]{43}$`)
+
+// ParseSubject extracts the token from an unsubscribe email's subject line,
+// if one was provided. Anything else after the prefix, such as a reader
+// typing "please", isn't a token.
+func ParseSubject(subject string) string {
+	fields := strings.Fields(strings.TrimPrefix(subject, SubjectPrefix))
+	if len(fields) == 0 || !tokenPattern.MatchString(fields[0]) {
+		return ""
+	}
+
+	return fields[0]
+}
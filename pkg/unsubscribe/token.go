@@ -0,0 +1,67 @@
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Sign creates an unsubscribe token for the provided reader. Tokens are
+// signed with OPERATOR_UNSUBSCRIBE_SECRET, so they can be verified without
+// trusting the address an unsubscribe request came from.
+func Sign(readerId int) (string, error) {
+	mac, err := tokenMAC(readerId)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d.%s", readerId, base64.RawURLEncoding.EncodeToString(mac)), nil
+}
+
+// Verify checks an unsubscribe token and returns the reader it was issued for.
+func Verify(token string) (int, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("malformed unsubscribe token")
+	}
+
+	readerId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("malformed unsubscribe token: %v", err)
+	}
+
+	actual, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("malformed unsubscribe token: %v", err)
+	}
+
+	expected, err := tokenMAC(readerId)
+	if err != nil {
+		return 0, err
+	}
+
+	if !hmac.Equal(actual, expected) {
+		return 0, fmt.Errorf("invalid unsubscribe token signature")
+	}
+
+	return readerId, nil
+}
+
+func tokenMAC(readerId int) ([]byte, error) {
+	secret := os.Getenv("OPERATOR_UNSUBSCRIBE_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("OPERATOR_UNSUBSCRIBE_SECRET is not set")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, err := mac.Write([]byte(fmt.Sprintf("unsubscribe:%d", readerId)))
+	if err != nil {
+		return nil, err
+	}
+
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v44/github"
+	"github.com/jackc/pgx"
+	"github.com/karashiiro/operator/pkg/pullrequests"
+	"github.com/karashiiro/operator/pkg/repos/plogons"
+)
+
+// maxPayloadSize is the largest webhook payload GitHub sends.
+const maxPayloadSize = 25 << 20
+
+// Handler receives GitHub webhooks for the plugin repository and keeps the
+// stored pull request states up to date.
+type Handler struct {
+	Pool   *pgx.ConnPool
+	Secret []byte
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only SHA-256 signatures are accepted
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		http.Error(w, "missing signature", http.StatusUnauthorized)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxPayloadSize)
+	payload, err := github.ValidatePayloadFromBody(r.Header.Get("Content-Type"), body, signature, h.Secret)
+	if err != nil {
+		log.Printf("Rejected webhook delivery %s: %v\n", github.DeliveryID(r), err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		// Events we didn't ask for aren't an error
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	conn, err := h.Pool.Acquire()
+	if err != nil {
+		log.Printf("Failed to acquire database connection: %v\n", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer h.Pool.Release(conn)
+
+	err = handleEvent(conn, event, payload)
+	if err != nil {
+		log.Printf("Unable to handle webhook delivery %s: %v\n", github.DeliveryID(r), err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleEvent(conn *pgx.Conn, event interface{}, payload []byte) error {
+	switch e := event.(type) {
+	case *github.PullRequestEvent:
+		if !isPluginRepo(e.GetRepo()) {
+			return nil
+		}
+
+		// Anything that can change what validation sees invalidates it
+		invalidate := false
+		switch e.GetAction() {
+		case "opened", "reopened", "synchronize", "edited":
+			invalidate = true
+		}
+
+		log.Printf("Pull request #%d %s\n", e.GetNumber(), e.GetAction())
+		err := pullrequests.Store(conn, e.GetPullRequest(), invalidate)
+		if err != nil || e.GetAction() != "closed" {
+			return err
+		}
+
+		// Whoever triggered the event closed the pull request, unless it
+		// was merged
+		resolvedBy := e.GetSender().GetLogin()
+		if e.GetPullRequest().GetMerged() {
+			resolvedBy = e.GetPullRequest().GetMergedBy().GetLogin()
+		}
+
+		return pullrequests.SetResolvedBy(conn, e.GetNumber(), resolvedBy)
+	case *github.PullRequestReviewEvent:
+		if !isPluginRepo(e.GetRepo()) {
+			return nil
+		}
+
+		log.Printf("Pull request #%d review %s\n", e.GetPullRequest().GetNumber(), e.GetAction())
+		err := pullrequests.Store(conn, e.GetPullRequest(), false)
+		if err != nil {
+			return err
+		}
+
+		return pullrequests.StoreReview(conn, e.GetPullRequest().GetNumber(), e.GetReview())
+	case *github.IssueCommentEvent:
+		if !isPluginRepo(e.GetRepo()) || !e.GetIssue().IsPullRequest() || e.GetAction() != "created" {
+			return nil
+		}
+
+		// Only comments by the submitter matter, as a response to reviews
+		if e.GetComment().GetUser().GetLogin() != e.GetIssue().GetUser().GetLogin() {
+			return nil
+		}
+
+		return pullrequests.RecordAuthorActivity(conn, e.GetIssue().GetNumber(), e.GetComment().GetCreatedAt())
+	case *github.StatusEvent:
+		if !isPluginRepo(e.GetRepo()) || e.GetContext() == plogons.ValidationCheck {
+			return nil
+		}
+
+		return pullrequests.UpdateCIStatus(conn, e.GetSHA())
+	case *github.CheckRunEvent:
+		if !isPluginRepo(e.GetRepo()) || e.GetCheckRun().GetName() == plogons.ValidationCheck {
+			return nil
+		}
+
+		return pullrequests.UpdateCIStatus(conn, e.GetCheckRun().GetHeadSHA())
+	case *github.LabelEvent:
+		if !isPluginRepo(e.GetRepo()) {
+			return nil
+		}
+
+		switch e.GetAction() {
+		case "edited":
+			oldName := e.GetLabel().GetName()
+			if from := labelRenamedFrom(payload); from != "" {
+				oldName = from
+			}
+
+			return pullrequests.UpdateLabel(conn, oldName, e.GetLabel())
+		case "deleted":
+			return pullrequests.UpdateLabel(conn, e.GetLabel().GetName(), nil)
+		}
+	}
+
+	return nil
+}
+
+// labelRenamedFrom returns the previous name of a renamed label. The label
+// event type doesn't include this, so it's read from the payload directly.
+func labelRenamedFrom(payload []byte) string {
+	var e struct {
+		Changes struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"changes"`
+	}
+
+	err := json.Unmarshal(payload, &e)
+	if err != nil {
+		return ""
+	}
+
+	return e.Changes.Name.From
+}
+
+func isPluginRepo(repo *github.Repository) bool {
+	return repo.GetOwner().GetLogin() == plogons.Owner && repo.GetName() == plogons.Repo
+}